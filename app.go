@@ -4,20 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/revengel/enpass2gopass/field"
 	"github.com/revengel/enpass2gopass/store"
-	"github.com/revengel/enpass2gopass/store/enpass"
-	"github.com/revengel/enpass2gopass/store/gopass"
+	"github.com/revengel/enpass2gopass/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-const (
-	EnpassJsonSourceType   = "enpassJsonSource"
-	GopassDestinationType  = "gopassDestination"
-	KeepassDestinationType = "keepassDestination"
-)
-
 type app struct {
 	ctx         context.Context
 	logger      *logrus.Logger
@@ -25,15 +23,31 @@ type app struct {
 	destination store.StoreDestination
 }
 
+// Close - closes source and destination, destination is closed even if
+// source fails so its pending changes are not lost
 func (a *app) Close() error {
-	var err error
+	var srcErr, dstErr error
+	if closer, ok := a.source.(io.Closer); ok {
+		srcErr = closer.Close()
+		if srcErr != nil {
+			srcErr = fmt.Errorf("failed to close source: %s", srcErr)
+		}
+	}
+	a.source = nil
+
 	if a.destination != nil {
-		err = a.destination.Close()
-		if err != nil {
-			return err
+		dstErr = a.destination.Close()
+		if dstErr != nil {
+			dstErr = fmt.Errorf("failed to close destination: %s", dstErr)
 		}
+		a.destination = nil
 	}
-	return nil
+
+	return errors.Join(srcErr, dstErr)
+}
+
+func (a *app) After(cmd *cobra.Command, args []string) error {
+	return a.Close()
 }
 
 func (a *app) SetLogLevel(cmd *cobra.Command, args []string) error {
@@ -58,6 +72,51 @@ func (a *app) SetLogLevel(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// env - provider settings from common flags
+func (a *app) env(cmd *cobra.Command) store.Env {
+	prefix, _ := cmd.Flags().GetString("prefix")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	return store.Env{Ctx: a.ctx, Prefix: prefix, DryRun: dryRun, Logger: a.logger}
+}
+
+// connectSource - creates source selected by source-provider flag,
+// env is completed with source description
+func (a *app) connectSource(cmd *cobra.Command, env *store.Env) error {
+	sourceName, _ := cmd.Flags().GetString("source-provider")
+	sourceProvider, ok := store.GetSource(sourceName)
+	if !ok {
+		return fmt.Errorf("invalid source provider: %s", sourceName)
+	}
+
+	var sourceOpts = newFlagOptions(cmd, sourceProvider.Provider)
+	err := sourceProvider.Validate(sourceOpts)
+	if err != nil {
+		return err
+	}
+
+	a.source, err = sourceProvider.New(*env, sourceOpts)
+	if err != nil {
+		return fmt.Errorf("failed to connect source: %s", err)
+	}
+
+	env.Source = sourceName
+	if sf, ok := a.source.(store.StoreSourceFile); ok {
+		env.SourceFile = sf.GetSourcePath()
+	}
+	return nil
+}
+
+// BeforeExtract - extract command reads source only
+func (a *app) BeforeExtract(cmd *cobra.Command, args []string) error {
+	err := a.SetLogLevel(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	var env = a.env(cmd)
+	return a.connectSource(cmd, &env)
+}
+
 func (a *app) Before(cmd *cobra.Command, args []string) error {
 	var err error
 	err = a.SetLogLevel(cmd, args)
@@ -65,41 +124,94 @@ func (a *app) Before(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	prefix, _ := cmd.Flags().GetString("prefix")
-	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	var env = a.env(cmd)
+	destNames, _ := cmd.Flags().GetStringSlice("destination-provider")
+	if len(destNames) == 0 {
+		return errors.New("destination provider is not set")
+	}
 
-	sourceProvider, _ := cmd.Flags().GetString("source-provider")
-	destProvider, _ := cmd.Flags().GetString("destination-provider")
+	var destProviders []store.DestinationProvider
+	for n, name := range destNames {
+		if utils.InList(destNames[:n], name) {
+			return fmt.Errorf("destination provider %s is set twice", name)
+		}
 
-	switch sourceProvider {
-	case EnpassJsonSourceType:
-		enpassJsonPath, _ := cmd.Flags().GetString("source-enpass-json-path")
-		if enpassJsonPath == "" {
-			return errors.New("source enpass json file is not set")
+		p, ok := store.GetDestination(name)
+		if !ok {
+			return fmt.Errorf("invalid destination provider: %s", name)
 		}
-		a.source, err = enpass.NewEnpassJsonSource(enpassJsonPath)
-	default:
-		return fmt.Errorf("invalid source provider: %s", sourceProvider)
+
+		err = p.Validate(newFlagOptions(cmd, p.Provider))
+		if err != nil {
+			return err
+		}
+		destProviders = append(destProviders, p)
 	}
 
+	err = a.connectSource(cmd, &env)
 	if err != nil {
-		return fmt.Errorf("failed to connect source: %s", err)
+		return err
 	}
 
-	switch destProvider {
-	case GopassDestinationType:
-		a.destination, err = gopass.NewStore(a.ctx, prefix, dryRun, a.logger)
-	default:
-		return fmt.Errorf("invalid destination provider: %s", destProvider)
+	if len(destProviders) == 1 {
+		a.destination, err = destProviders[0].New(env, newFlagOptions(cmd, destProviders[0].Provider))
+		if err != nil {
+			return fmt.Errorf("failed to connect destination: %s", err)
+		}
+		return nil
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to connect destination: %s", err)
+	// several destinations are written in one run, failed one either aborts
+	// the run or is only reported and skipped further
+	continueOnError, _ := cmd.Flags().GetBool("destination-continue-on-error")
+	multi := store.NewMultiDestination(continueOnError, a.logger)
+	a.destination = multi
+	for _, p := range destProviders {
+		var destEnv = env
+		destEnv.Logger = destinationLogger(a.logger, p.Name)
+
+		d, err := p.New(destEnv, newFlagOptions(cmd, p.Provider))
+		if err != nil && continueOnError {
+			a.logger.WithField("destination", p.Name).Errorf("failed to connect destination: %s", err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to connect destination %s: %s", p.Name, err)
+		}
+		multi.Add(p.Name, d)
+	}
+
+	if multi.Len() == 0 {
+		return errors.New("failed to connect any destination")
 	}
 
 	return nil
 }
 
+// destinationHook - adds destination name to every log entry
+type destinationHook string
+
+// Levels -
+func (h destinationHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire -
+func (h destinationHook) Fire(e *logrus.Entry) error {
+	e.Data["destination"] = string(h)
+	return nil
+}
+
+// destinationLogger - logger of single destination among several ones
+func destinationLogger(logger *logrus.Logger, provider string) *logrus.Logger {
+	l := logrus.New()
+	l.SetFormatter(logger.Formatter)
+	l.SetOutput(logger.Out)
+	l.SetLevel(logger.GetLevel())
+	l.AddHook(destinationHook(provider))
+	return l
+}
+
 func (a *app) Import(cmd *cobra.Command, args []string) error {
 	items, err := a.source.LoadData()
 	if err != nil {
@@ -130,3 +242,129 @@ func (a *app) Import(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// inPaths - checks whether secret path is one of paths or under one of them
+func inPaths(secretPath string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+
+	for _, p := range paths {
+		p = strings.Trim(p, "/")
+		if secretPath == p || strings.HasPrefix(secretPath, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentFilePath - file path of attachment under output directory,
+// names coming from store must not point outside of it
+func attachmentFilePath(output, secretPath, name string) (string, error) {
+	name = filepath.Base(filepath.Clean("/" + name))
+	if name == "/" || name == "." {
+		name = "attachment"
+	}
+
+	var p = filepath.Join(output, filepath.FromSlash(secretPath), name)
+	rel, err := filepath.Rel(output, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid attachment path: '%s'", p)
+	}
+	return p, nil
+}
+
+// writeAttachment - writes attachment file, returns false when file is already up to date;
+// checksums recorded by destination are verified by source when attachment is decoded
+func (a *app) writeAttachment(p string, data []byte, force, dryRun bool) (bool, error) {
+	var sum = utils.GetHashFromBytes(data)
+	existing, err := os.ReadFile(p)
+	switch {
+	case err == nil && utils.GetHashFromBytes(existing) == sum:
+		return false, nil
+	case err == nil && !force:
+		return false, fmt.Errorf("file '%s' already exists with other content, use --force to overwrite it", p)
+	case err != nil && !os.IsNotExist(err):
+		return false, err
+	}
+
+	if dryRun {
+		return true, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0o700)
+	if err != nil {
+		return false, err
+	}
+
+	err = os.WriteFile(p, data, 0o600)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (a *app) Extract(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	items, err := a.source.LoadData()
+	if err != nil {
+		return fmt.Errorf("Cannot load data from source: %s", err.Error())
+	}
+
+	var files = make(map[string]string)
+	var count int
+	for _, item := range items {
+		secretPath, err := item.GetSecretPath()
+		if err != nil {
+			return fmt.Errorf("cannot get seceret path: %s", err.Error())
+		}
+
+		if !inPaths(secretPath, args) {
+			continue
+		}
+
+		fields, err := item.GetFields()
+		if err != nil {
+			return fmt.Errorf("cannot get item fields; secret key - '%s': %s", secretPath, err.Error())
+		}
+
+		for _, f := range fields {
+			if !f.IsType(field.SecretAttachmentField) {
+				continue
+			}
+
+			p, err := attachmentFilePath(output, secretPath, f.GetKey())
+			if err != nil {
+				return err
+			}
+
+			if other, ok := files[p]; ok {
+				return fmt.Errorf("attachments of '%s' and '%s' have the same file path '%s'", other, secretPath, p)
+			}
+			files[p] = secretPath
+
+			changed, err := a.writeAttachment(p, f.GetValue(), force, dryRun)
+			if err != nil {
+				return fmt.Errorf("cannot extract attachment; secret key - '%s': %s", secretPath, err.Error())
+			}
+
+			var l = a.logger.WithFields(logrus.Fields{
+				"path":   p,
+				"sha256": utils.GetHashFromBytes(f.GetValue()),
+			})
+			if !changed {
+				l.Debug("attachment file is up to date")
+				continue
+			}
+
+			count++
+			l.Info("attachment has been extracted")
+		}
+	}
+
+	a.logger.WithField("count", count).Info("attachments have been extracted")
+	return nil
+}
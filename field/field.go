@@ -7,7 +7,7 @@ const (
 	SecretUsernameField = "username"
 	// SecretPasswordField - secret field password type
 	SecretPasswordField = "password"
-	// SecretURLField -
+	// SecretURLField - secret field url type
 	SecretURLField = "url"
 	// SecretTagsField -
 	SecretTagsField = "tags"
@@ -15,6 +15,10 @@ const (
 	SecretSimpleField = "simple"
 	// SecretAttachmentField - secret field multiline type
 	SecretAttachmentField = "attachment"
+	// SecretIDField - stable identity of source item
+	SecretIDField = "id"
+	// SecretOTPField - otpauth uri of one-time password generator
+	SecretOTPField = "otp"
 )
 
 type FieldType string
@@ -87,8 +91,9 @@ func NewUsernameField(k, v string) FieldInterface {
 	return NewField(vOrDef(k, "username"), []byte(v), SecretUsernameField, false, false)
 }
 
+// NewUrlField - url has its own type, destinations write it to their url slot
 func NewUrlField(k, v string) FieldInterface {
-	return NewField(vOrDef(k, "url"), []byte(v), SecretUsernameField, false, false)
+	return NewField(vOrDef(k, "url"), []byte(v), SecretURLField, false, false)
 }
 
 func NewTagsField(k, v string) FieldInterface {
@@ -102,3 +107,12 @@ func NewAttachmentField(k string, v []byte) FieldInterface {
 func NewPasswordField(k, v string) FieldInterface {
 	return NewField(vOrDef(k, "password"), []byte(v), SecretPasswordField, false, true)
 }
+
+func NewIDField(k, v string) FieldInterface {
+	return NewField(vOrDef(k, "id"), []byte(v), SecretIDField, false, false)
+}
+
+// NewOTPField - key defaults to otpauth, the key gopass otp looks for
+func NewOTPField(k, v string) FieldInterface {
+	return NewField(vOrDef(k, "otpauth"), []byte(v), SecretOTPField, false, true)
+}
@@ -0,0 +1,35 @@
+package field
+
+import "testing"
+
+func TestFieldConstructors(t *testing.T) {
+	var tests = []struct {
+		f         FieldInterface
+		key       string
+		t         FieldType
+		sensitive bool
+	}{
+		{f: NewTitleField("", "v"), key: "title", t: SecretTitleField},
+		{f: NewUsernameField("", "v"), key: "username", t: SecretUsernameField},
+		{f: NewPasswordField("", "v"), key: "password", t: SecretPasswordField, sensitive: true},
+		{f: NewUrlField("", "v"), key: "url", t: SecretURLField},
+		{f: NewUrlField("website", "v"), key: "website", t: SecretURLField},
+		{f: NewTagsField("", "v"), key: "tags", t: SecretTagsField},
+		{f: NewIDField("", "v"), key: "id", t: SecretIDField},
+		{f: NewOTPField("", "v"), key: "otpauth", t: SecretOTPField, sensitive: true},
+		{f: NewSimpleField("note", []byte("v"), true, false), key: "note", t: SecretSimpleField},
+		{f: NewField("k", []byte("v"), "", false, false), key: "k", t: SecretSimpleField},
+	}
+
+	for _, tt := range tests {
+		if tt.f.GetKey() != tt.key {
+			t.Errorf("expected key %q, got %q", tt.key, tt.f.GetKey())
+		}
+		if !tt.f.IsType(tt.t) {
+			t.Errorf("%s: expected type %q, got %q", tt.key, tt.t, tt.f.GetType())
+		}
+		if tt.f.IsSensitive() != tt.sensitive {
+			t.Errorf("%s: expected sensitive %v", tt.key, tt.sensitive)
+		}
+	}
+}
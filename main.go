@@ -6,6 +6,8 @@ import (
 	"os"
 	"os/signal"
 
+	"github.com/revengel/enpass2gopass/store/enpass"
+	"github.com/revengel/enpass2gopass/store/gopass"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -68,20 +70,44 @@ func main() {
 	}
 
 	importCmd := &cobra.Command{
-		Use:     "import",
-		Short:   "Import command",
-		Aliases: []string{},
-		PreRunE: a.Before,
-		RunE:    a.Import,
+		Use:      "import",
+		Short:    "Import command",
+		Aliases:  []string{},
+		PreRunE:  a.Before,
+		RunE:     a.Import,
+		PostRunE: a.After,
 	}
 
 	importCmd.PersistentFlags().StringP("prefix", "", "", "destination storage path prefix")
 	importCmd.PersistentFlags().BoolP("dry-run", "", false, "do not make changes")
-	importCmd.PersistentFlags().StringP("source-provider", "", EnpassJsonSourceType, "source provider")
-	importCmd.PersistentFlags().StringP("source-enpass-json-path", "", "", "source enpass json path")
-	importCmd.PersistentFlags().StringP("destination-provider", "", GopassDestinationType, "destination provider")
+	importCmd.PersistentFlags().StringP("source-provider", "", enpass.JsonSourceName, "source provider, see providers command")
+	importCmd.PersistentFlags().StringSliceP("destination-provider", "", []string{gopass.DestinationName}, "destination providers, comma separated or repeated to write several destinations in one run")
+	importCmd.PersistentFlags().BoolP("destination-continue-on-error", "", false, "report failed destination and continue with the others instead of aborting the run")
+	addSourceFlags(importCmd)
+	addDestinationFlags(importCmd)
 
-	rootCmd.AddCommand(versionCmd, importCmd)
+	extractCmd := &cobra.Command{
+		Use:      "extract [secret path...]",
+		Short:    "restore attachments from store to files",
+		Long:     "Restore attachments of secrets to <output>/<secret path>/<file name>, checksums recorded by destination are verified.",
+		PreRunE:  a.BeforeExtract,
+		RunE:     a.Extract,
+		PostRunE: a.After,
+	}
+
+	extractCmd.PersistentFlags().StringP("output", "o", ".", "output directory")
+	extractCmd.PersistentFlags().BoolP("force", "", false, "overwrite existing files with other content")
+	extractCmd.PersistentFlags().BoolP("dry-run", "", false, "do not write files")
+	extractCmd.PersistentFlags().StringP("source-provider", "", gopass.SourceName, "source provider, see providers command")
+	addSourceFlags(extractCmd)
+
+	providersCmd := &cobra.Command{
+		Use:   "providers",
+		Short: "show sources and destinations with their options",
+		Run:   listProviders,
+	}
+
+	rootCmd.AddCommand(versionCmd, importCmd, extractCmd, providersCmd)
 
 	err = rootCmd.ExecuteContext(ctx)
 	if err != nil {
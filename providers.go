@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	// providers register themselves in store registry
+	_ "github.com/revengel/enpass2gopass/store/agedir"
+	_ "github.com/revengel/enpass2gopass/store/bitwarden"
+	_ "github.com/revengel/enpass2gopass/store/csv"
+	_ "github.com/revengel/enpass2gopass/store/dotenv"
+	_ "github.com/revengel/enpass2gopass/store/enpass"
+	_ "github.com/revengel/enpass2gopass/store/gopass"
+	_ "github.com/revengel/enpass2gopass/store/keepass"
+	_ "github.com/revengel/enpass2gopass/store/onepassword"
+	_ "github.com/revengel/enpass2gopass/store/pass"
+	_ "github.com/revengel/enpass2gopass/store/sops"
+	_ "github.com/revengel/enpass2gopass/store/vault"
+)
+
+// flagOptions - provider options backed by command flags
+type flagOptions struct {
+	flags    *pflag.FlagSet
+	provider store.Provider
+}
+
+func newFlagOptions(cmd *cobra.Command, p store.Provider) flagOptions {
+	return flagOptions{flags: cmd.Flags(), provider: p}
+}
+
+func (o flagOptions) String(name string) string {
+	v, _ := o.flags.GetString(o.provider.Flag(name))
+	return v
+}
+
+func (o flagOptions) Strings(name string) []string {
+	v, _ := o.flags.GetStringSlice(o.provider.Flag(name))
+	return v
+}
+
+func (o flagOptions) Bool(name string) bool {
+	v, _ := o.flags.GetBool(o.provider.Flag(name))
+	return v
+}
+
+func (o flagOptions) Int(name string) int {
+	v, _ := o.flags.GetInt(o.provider.Flag(name))
+	return v
+}
+
+func (o flagOptions) Uint32(name string) uint32 {
+	v, _ := o.flags.GetUint32(o.provider.Flag(name))
+	return v
+}
+
+func (o flagOptions) Uint64(name string) uint64 {
+	v, _ := o.flags.GetUint64(o.provider.Flag(name))
+	return v
+}
+
+func (o flagOptions) Changed(name string) bool {
+	return o.flags.Changed(o.provider.Flag(name))
+}
+
+// addOptionFlag - defines flag of provider option
+func addOptionFlag(flags *pflag.FlagSet, p store.Provider, o store.Option) {
+	var name = p.Flag(o.Name)
+	switch o.Type {
+	case store.OptionString:
+		def, _ := o.Default.(string)
+		flags.String(name, def, o.Description)
+	case store.OptionStrings:
+		def, _ := o.Default.([]string)
+		flags.StringSlice(name, def, o.Description)
+	case store.OptionBool:
+		def, _ := o.Default.(bool)
+		flags.Bool(name, def, o.Description)
+	case store.OptionInt:
+		def, _ := o.Default.(int)
+		flags.Int(name, def, o.Description)
+	case store.OptionUint32:
+		def, _ := o.Default.(uint32)
+		flags.Uint32(name, def, o.Description)
+	case store.OptionUint64:
+		def, _ := o.Default.(uint64)
+		flags.Uint64(name, def, o.Description)
+	default:
+		panic(fmt.Sprintf("option %s of %s provider has unsupported type %s", o.Name, p.Name, o.Type))
+	}
+}
+
+// addSourceFlags - defines flags of all registered sources
+func addSourceFlags(cmd *cobra.Command) {
+	var flags = cmd.PersistentFlags()
+	for _, p := range store.Sources() {
+		for _, o := range p.Options {
+			addOptionFlag(flags, p.Provider, o)
+		}
+	}
+}
+
+// addDestinationFlags - defines flags of all registered destinations
+func addDestinationFlags(cmd *cobra.Command) {
+	var flags = cmd.PersistentFlags()
+	for _, p := range store.Destinations() {
+		for _, o := range p.Options {
+			addOptionFlag(flags, p.Provider, o)
+		}
+	}
+}
+
+// printProviders - writes providers with their options
+func printProviders(w *tabwriter.Writer, title string, providers []store.Provider) {
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, p := range providers {
+		fmt.Fprintf(w, "  %s\t%s\n", p.Name, p.Description)
+		for _, o := range p.Options {
+			var details []string
+			if o.Required {
+				details = append(details, "required")
+			}
+			if def := fmt.Sprint(o.Default); o.Default != nil && def != "" && def != "[]" && def != "false" {
+				details = append(details, "default "+def)
+			}
+
+			var description = o.Description
+			if len(details) > 0 {
+				description = fmt.Sprintf("%s (%s)", description, strings.Join(details, ", "))
+			}
+			fmt.Fprintf(w, "      --%s %s\t%s\n", p.Flag(o.Name), o.Type, description)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// listProviders - providers command
+func listProviders(cmd *cobra.Command, args []string) {
+	var sources, destinations []store.Provider
+	for _, p := range store.Sources() {
+		sources = append(sources, p.Provider)
+	}
+	for _, p := range store.Destinations() {
+		destinations = append(destinations, p.Provider)
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	printProviders(w, "Sources", sources)
+	printProviders(w, "Destinations", destinations)
+	_ = w.Flush()
+}
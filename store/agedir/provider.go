@@ -0,0 +1,46 @@
+package agedir
+
+import (
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+// DestinationName -
+const DestinationName = "ageDestination"
+
+func init() {
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        DestinationName,
+			Description: "directory of age encrypted files, decryptable with age cli",
+			FlagPrefix:  "destination-age",
+			Options: []store.Option{
+				store.StringOption("path", "", "destination directory for age encrypted files").Require(),
+				store.StringsOption("recipients", nil, "age or ssh public keys to encrypt destination files to"),
+				store.StringOption("recipients-file", "", "file with age or ssh public keys, one per line"),
+				store.StringOption("identity", "", "age identity or ssh private key to compare existing destination files, they are rewritten if empty"),
+				store.StringOption("passphrase-env", "AGE_PASSPHRASE", "environment variable with destination age passphrase, used if there are no recipients"),
+				store.StringOption("passphrase-file", "", "file with destination age passphrase"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			var recipients, recipientsPath = opts.Strings("recipients"), opts.String("recipients-file")
+
+			var passphrase string
+			if len(recipients) == 0 && recipientsPath == "" {
+				var err error
+				passphrase, _, err = utils.ReadPassword(opts.String("passphrase-env"), opts.String("passphrase-file"), true, "Age passphrase: ")
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			st, err := NewStore(opts.String("path"), recipients, recipientsPath, opts.String("identity"), passphrase,
+				env.Prefix, env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
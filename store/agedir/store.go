@@ -0,0 +1,228 @@
+package agedir
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/store/secretdir"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	secretExt = ".age"
+
+	// recipientsFile - public recipients of files under prefix, when they change
+	// all files are encrypted again even if their content is the same
+	recipientsFile = ".age-recipients"
+
+	passphraseRecipients = "scrypt"
+)
+
+// Store - writes every secret as age encrypted file, decryptable with age cli
+type Store struct {
+	root       string
+	prefix     string
+	recipients []age.Recipient
+	identities []age.Identity
+	markerData string
+	rekey      bool
+	items      *utils.UniqueStrings
+	written    map[string]bool
+	dryrun     bool
+	logger     *logrus.Logger
+}
+
+// Close - records recipients of written files
+func (st *Store) Close() error {
+	if st.dryrun || !st.rekey || len(st.written) == 0 {
+		return nil
+	}
+	return secretdir.WriteFile(filepath.Join(st.root, st.prefix, recipientsFile), []byte(st.markerData))
+}
+
+// needsUpdate - content of existing file is compared only if it can be decrypted
+func (st *Store) needsUpdate(p string, data []byte) bool {
+	if st.rekey {
+		return true
+	}
+
+	current, err := os.ReadFile(p)
+	if err != nil {
+		return true
+	}
+
+	if len(st.identities) == 0 {
+		st.logger.WithField("agekey", p).Debug("age identity is not set, existing secret will be rewritten")
+		return true
+	}
+
+	plain, err := decrypt(current, st.identities)
+	if err != nil {
+		st.logger.WithField("agekey", p).Debug("existing secret cannot be decrypted and will be rewritten")
+		return true
+	}
+
+	return !bytes.Equal(plain, data)
+}
+
+// saveSecret -
+func (st *Store) saveSecret(data []byte, name string) (bool, error) {
+	var p = filepath.Join(st.root, filepath.FromSlash(name)+secretExt)
+	st.written[p] = true
+	var l = st.logger.WithField("agekey", name)
+
+	if !st.needsUpdate(p, data) {
+		l.Debug("age secret already in actual state")
+		return false, nil
+	}
+
+	l.Info("secret will be updated")
+	if st.dryrun {
+		return true, nil
+	}
+
+	encrypted, err := encrypt(data, st.recipients)
+	if err != nil {
+		return false, fmt.Errorf("cannot encrypt secret: %s", err.Error())
+	}
+
+	err = secretdir.WriteFile(p, encrypted)
+	if err != nil {
+		return false, err
+	}
+
+	l.Info("secret has been updated")
+	return true, nil
+}
+
+// Save - secrets use the same layout as gopass destination, attachments are stored as is
+func (st *Store) Save(fields []field.FieldInterface, p string) (bool, error) {
+	p = st.items.Unique(p)
+
+	var secretFields []field.FieldInterface
+	var attachments = make(map[string][]byte)
+	for _, f := range fields {
+		if f.IsType(field.SecretAttachmentField) {
+			attachments[f.GetKey()] = f.GetValue()
+			continue
+		}
+		secretFields = append(secretFields, f)
+	}
+
+	mainSecret, err := secretdir.AKVSecret(secretFields)
+	if err != nil {
+		return false, err
+	}
+
+	out, err := st.saveSecret(mainSecret.Bytes(), secretdir.MainPath(st.prefix, p))
+	if err != nil {
+		return out, err
+	}
+
+	for name, data := range attachments {
+		changed, err := st.saveSecret(data, secretdir.AttachmentPath(st.prefix, p, name))
+		if err != nil {
+			return out, err
+		}
+		out = out || changed
+	}
+
+	return out, nil
+}
+
+// Cleanup - removes secrets under prefix which were not saved during this run
+func (st *Store) Cleanup() (bool, error) {
+	return secretdir.Cleanup(st.root, st.prefix, secretExt, st.written, st.dryrun, func(name string) {
+		st.logger.WithField("type", "cleaner").
+			WithField("agekey", name).
+			Info("age secret will be deleted")
+	})
+}
+
+// NewStore - files are encrypted to recipients or, if there are none, with passphrase;
+// identities file is used to skip rewriting of unchanged secrets
+func NewStore(dirPath string, recipients []string, recipientsPath, identityPath, passphrase, prefix string,
+	dryrun bool, logger *logrus.Logger) (st *Store, err error) {
+	if dirPath == "" {
+		return nil, errors.New("destination age directory is not set")
+	}
+
+	if recipientsPath != "" {
+		fromFile, err := readRecipientsFile(recipientsPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read age recipients file: %s", err.Error())
+		}
+		recipients = append(append([]string(nil), recipients...), fromFile...)
+	}
+
+	root, err := filepath.Abs(dirPath)
+	if err != nil {
+		return
+	}
+
+	if prefix == "" {
+		prefix = "enpass"
+	}
+
+	st = &Store{
+		root:    root,
+		prefix:  prefix,
+		items:   utils.NewUniqueStrings(logger),
+		written: make(map[string]bool),
+		dryrun:  dryrun,
+		logger:  logger,
+	}
+
+	switch {
+	case len(recipients) > 0:
+		for _, s := range recipients {
+			r, err := parseRecipient(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid age recipient '%s': %s", s, err.Error())
+			}
+			st.recipients = append(st.recipients, r)
+		}
+
+		var sorted = append([]string(nil), recipients...)
+		sort.Strings(sorted)
+		st.markerData = strings.Join(sorted, "\n") + "\n"
+	case passphrase != "":
+		r, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		st.recipients = []age.Recipient{r}
+		st.identities = []age.Identity{id}
+		st.markerData = passphraseRecipients + "\n"
+	default:
+		return nil, errors.New("age recipients or passphrase are not set")
+	}
+
+	if identityPath != "" {
+		ids, err := readIdentities(identityPath)
+		if err != nil {
+			return nil, err
+		}
+		st.identities = append(st.identities, ids...)
+	}
+
+	current, err := os.ReadFile(filepath.Join(root, prefix, recipientsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	st.rekey = string(current) != st.markerData
+
+	return st, nil
+}
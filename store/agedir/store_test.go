@@ -0,0 +1,191 @@
+package agedir
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Logger {
+	var l = logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+// testIdentity - generates throwaway identity and writes it into identities file
+func testIdentity(t *testing.T) (*age.X25519Identity, string) {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p = filepath.Join(t.TempDir(), "identity.txt")
+	if err = os.WriteFile(p, []byte(id.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return id, p
+}
+
+// readSecret - decrypts secret file the way age cli does
+func readSecret(t *testing.T, p string, id age.Identity) string {
+	t.Helper()
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, err := age.Decrypt(f, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func itemFields(password string) []field.FieldInterface {
+	return []field.FieldInterface{
+		field.NewUsernameField("", "user"),
+		field.NewPasswordField("", password),
+		field.NewIDField("enpass_uuid", "0b3c7f6e"),
+		field.NewSimpleField("notes", []byte("line1\nline2"), true, false),
+		field.NewAttachmentField("key.bin", []byte{0x00, 0xff, 0x10}),
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	id, identityPath := testIdentity(t)
+	var root = t.TempDir()
+
+	st, err := NewStore(root, []string{id.Recipient().String()}, "", identityPath, "", "", false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := st.Save(itemFields("pass"), "web/site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("new secret is not reported as changed")
+	}
+	if err = st.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var main = readSecret(t, filepath.Join(root, "enpass", "web", "site", "data.age"), id)
+	if !strings.HasPrefix(main, "pass\n") {
+		t.Errorf("password is not on first line:\n%s", main)
+	}
+	if !strings.Contains(main, "username: user\n") || !strings.Contains(main, "notes\n\nline1\nline2\n") {
+		t.Errorf("secret lost fields:\n%s", main)
+	}
+	if strings.Contains(main, "enpass_uuid") {
+		t.Errorf("secret contains source id:\n%s", main)
+	}
+
+	var attachment = readSecret(t, filepath.Join(root, "enpass", "web", "site", "attachments", "key.bin.age"), id)
+	if !bytes.Equal([]byte(attachment), []byte{0x00, 0xff, 0x10}) {
+		t.Errorf("attachment is not stored as is: %x", attachment)
+	}
+
+	marker, err := os.ReadFile(filepath.Join(root, "enpass", recipientsFile))
+	if err != nil || string(marker) != id.Recipient().String()+"\n" {
+		t.Errorf("recipients are not recorded: %q, %v", marker, err)
+	}
+
+	// existing files are compared after decryption, every run is a new store
+	for _, tc := range []struct {
+		password string
+		changed  bool
+	}{{"pass", false}, {"new", true}} {
+		st, err = NewStore(root, []string{id.Recipient().String()}, "", identityPath, "", "", false, testLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changed, err = st.Save(itemFields(tc.password), "web/site")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != tc.changed {
+			t.Errorf("password %s: expected changed %v, got %v", tc.password, tc.changed, changed)
+		}
+	}
+
+	if main = readSecret(t, filepath.Join(root, "enpass", "web", "site", "data.age"), id); !strings.HasPrefix(main, "new\n") {
+		t.Errorf("secret is not updated:\n%s", main)
+	}
+}
+
+func TestStorePassphrase(t *testing.T) {
+	var root = t.TempDir()
+	st, err := NewStore(root, nil, "", "", "secret", "", false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = st.Save(itemFields("pass"), "site"); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := age.NewScryptIdentity("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if main := readSecret(t, filepath.Join(root, "enpass", "site", "data.age"), id); !strings.HasPrefix(main, "pass\n") {
+		t.Errorf("password is not on first line:\n%s", main)
+	}
+}
+
+func TestStoreCleanup(t *testing.T) {
+	id, _ := testIdentity(t)
+	var root = t.TempDir()
+	var stale = filepath.Join(root, "enpass", "old", "data.age")
+	var foreign = filepath.Join(root, "other", "data.age")
+	for _, p := range []string{stale, foreign} {
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st, err := NewStore(root, []string{id.Recipient().String()}, "", "", "", "", false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.Save(itemFields("pass"), "site"); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := st.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("cleanup is not reported as changed")
+	}
+
+	if _, err = os.Stat(filepath.Dir(stale)); !os.IsNotExist(err) {
+		t.Error("stale secret and its directory are not removed")
+	}
+	for _, p := range []string{foreign, filepath.Join(root, "enpass", "site", "data.age")} {
+		if _, err = os.Stat(p); err != nil {
+			t.Errorf("secret %s is removed: %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package attachment
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+// attachment secrets are shared by gopass and pass destinations and gopass source
+
+const (
+	dispositionKey = "Content-Disposition"
+	encodingKey    = "Content-Transfer-Encoding"
+	// checksumKey - hex sha256 of decoded content, same as `gopass sha256` prints
+	checksumKey = "Sha256"
+)
+
+// NewSecret - attachment secret in layout of `gopass fscopy`,
+// so `gopass cat` and `gopass fscopy` restore original bytes
+func NewSecret(name string, data []byte) (*secrets.AKV, error) {
+	var secret = secrets.NewAKV()
+	err := secret.Set(dispositionKey, fmt.Sprintf("attachment; filename=\"%s\"", name))
+	if err != nil {
+		return nil, err
+	}
+
+	err = secret.Set(encodingKey, "Base64")
+	if err != nil {
+		return nil, err
+	}
+
+	err = secret.Set(checksumKey, utils.GetHashFromBytes(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// stored secret body is terminated with newline by gopass parser
+	_, err = secret.Write([]byte(base64.StdEncoding.EncodeToString(data) + "\n"))
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// body - returns part of raw secret after password and header lines;
+// gopass AKV parser drops lines of body which look like key-value pairs
+func body(raw string) string {
+	var lines = strings.Split(raw, "\n")
+	for n := 1; n < len(lines); n++ {
+		if strings.TrimSpace(lines[n]) == "---" {
+			return strings.Join(lines[n+1:], "\n")
+		}
+		if !strings.Contains(lines[n], ": ") {
+			return strings.Join(lines[n:], "\n")
+		}
+	}
+	return ""
+}
+
+// Decode - returns file name and content of attachment secret,
+// content is checked against recorded checksum; ok is false for other secrets
+func Decode(name string, sec gopass.Secret) (filename string, data []byte, ok bool, err error) {
+	disposition, ok := sec.Get(dispositionKey)
+	if !ok {
+		return "", nil, false, nil
+	}
+
+	filename = name
+	_, params, err := mime.ParseMediaType(disposition)
+	if err == nil && params["filename"] != "" {
+		filename = params["filename"]
+	}
+
+	var b = body(string(sec.Bytes()))
+	// older versions stored raw content despite the header,
+	// gopass parser terminates it with newline
+	data = []byte(strings.TrimSuffix(b, "\n"))
+	if enc, _ := sec.Get(encodingKey); strings.EqualFold(enc, "base64") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(b), ""))
+		if err == nil {
+			data = decoded
+		}
+	}
+
+	if sum, found := sec.Get(checksumKey); found && !strings.EqualFold(sum, utils.GetHashFromBytes(data)) {
+		return filename, nil, true, fmt.Errorf("checksum mismatch of attachment '%s'", filename)
+	}
+
+	return filename, data, true, nil
+}
@@ -0,0 +1,74 @@
+package attachment
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+func TestSecretRoundTrip(t *testing.T) {
+	var data = []byte("line: one\n\x00\xffbinary\n")
+	sec, err := NewSecret("scan.bin", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// secret is decoded as stored by gopass
+	filename, decoded, ok, err := Decode("scan_bin", secrets.ParseAKV(sec.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || filename != "scan.bin" || !bytes.Equal(decoded, data) {
+		t.Errorf("Decode = %q, %q, %v, expected scan.bin, %q, true", filename, decoded, ok, data)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var tests = []struct {
+		name     string
+		raw      string
+		filename string
+		data     string
+		ok       bool
+		err      bool
+	}{
+		{
+			name: "regular secret",
+			raw:  "password\nusername: user\n",
+		},
+		{
+			name:     "raw content",
+			raw:      "\nContent-Disposition: attachment; filename=\"notes.txt\"\nfirst line\nsecond line\n",
+			filename: "notes.txt",
+			data:     "first line\nsecond line",
+			ok:       true,
+		},
+		{
+			name:     "checksum mismatch",
+			raw:      "\nContent-Disposition: attachment; filename=\"notes.txt\"\nContent-Transfer-Encoding: Base64\nSha256: 00\nY29udGVudA==\n",
+			filename: "notes.txt",
+			ok:       true,
+			err:      true,
+		},
+		{
+			name:     "file name from secret name",
+			raw:      "\nContent-Disposition: attachment\nContent-Transfer-Encoding: Base64\nY29udGVudA==\n",
+			filename: "file",
+			data:     "content",
+			ok:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename, data, ok, err := Decode("file", secrets.ParseAKV([]byte(tt.raw)))
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.ok || filename != tt.filename || string(data) != tt.data {
+				t.Errorf("Decode = %q, %q, %v, expected %q, %q, %v", filename, data, ok, tt.filename, tt.data, tt.ok)
+			}
+		})
+	}
+}
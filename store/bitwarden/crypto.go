@@ -0,0 +1,193 @@
+package bitwarden
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// encTypeAesCbc256HmacSha256 - the only cipher string type used by password protected exports
+const encTypeAesCbc256HmacSha256 = "2"
+
+// ErrInvalidPassword -
+var ErrInvalidPassword = errors.New("invalid export password")
+
+// symmetricKey - stretched encryption and mac keys
+type symmetricKey struct {
+	enc []byte
+	mac []byte
+}
+
+// deriveKey - derives export key from password like bitwarden clients do
+func deriveKey(password, salt string, kdfType, iterations, memory, parallelism int) (k symmetricKey, err error) {
+	var master []byte
+	switch kdfType {
+	case KdfTypePBKDF2:
+		if iterations <= 0 {
+			return k, errors.New("invalid pbkdf2 iterations")
+		}
+		master = pbkdf2.Key([]byte(password), []byte(salt), iterations, 32, sha256.New)
+	case KdfTypeArgon2id:
+		if iterations <= 0 || memory <= 0 || parallelism <= 0 {
+			return k, errors.New("invalid argon2 parameters")
+		}
+		var saltHash = sha256.Sum256([]byte(salt))
+		master = argon2.IDKey([]byte(password), saltHash[:], uint32(iterations), uint32(memory*1024), uint8(parallelism), 32)
+	default:
+		return k, fmt.Errorf("unsupported kdf type: %d", kdfType)
+	}
+
+	k.enc, err = expandKey(master, "enc")
+	if err != nil {
+		return
+	}
+	k.mac, err = expandKey(master, "mac")
+	return
+}
+
+func expandKey(master []byte, info string) ([]byte, error) {
+	var out = make([]byte, 32)
+	_, err := io.ReadFull(hkdf.Expand(sha256.New, master, []byte(info)), out)
+	return out, err
+}
+
+// decryptString - decrypts "2.iv|data|mac" cipher string
+func (k symmetricKey) decryptString(s string) ([]byte, error) {
+	encType, rest, ok := strings.Cut(s, ".")
+	if !ok || encType != encTypeAesCbc256HmacSha256 {
+		return nil, fmt.Errorf("unsupported cipher string type: '%s'", encType)
+	}
+
+	var parts = strings.Split(rest, "|")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid cipher string")
+	}
+
+	var raw [3][]byte
+	for i, p := range parts {
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			return nil, err
+		}
+		raw[i] = b
+	}
+
+	iv, data, mac := raw[0], raw[1], raw[2]
+	if !hmac.Equal(k.sign(iv, data), mac) {
+		return nil, ErrInvalidPassword
+	}
+
+	if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid cipher string data")
+	}
+
+	block, err := aes.NewCipher(k.enc)
+	if err != nil {
+		return nil, err
+	}
+
+	var out = make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+
+	var pad = int(out[len(out)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(out[len(out)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, errors.New("invalid padding")
+	}
+	return out[:len(out)-pad], nil
+}
+
+// encryptString - encrypts data into "2.iv|data|mac" cipher string
+func (k symmetricKey) encryptString(data []byte) (string, error) {
+	var iv = make([]byte, aes.BlockSize)
+	_, err := rand.Read(iv)
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(k.enc)
+	if err != nil {
+		return "", err
+	}
+
+	var pad = aes.BlockSize - len(data)%aes.BlockSize
+	var out = append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, out)
+
+	return fmt.Sprintf("%s.%s|%s|%s", encTypeAesCbc256HmacSha256,
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(out),
+		base64.StdEncoding.EncodeToString(k.sign(iv, out)),
+	), nil
+}
+
+// decryptExport - decrypts password protected export
+func decryptExport(e Export, password string) (out Export, err error) {
+	key, err := deriveKey(password, e.Salt, e.KdfType, e.KdfIterations, e.KdfMemory, e.KdfParallelism)
+	if err != nil {
+		return
+	}
+
+	_, err = key.decryptString(e.EncKeyValidation)
+	if err != nil {
+		return out, ErrInvalidPassword
+	}
+
+	data, err := key.decryptString(e.Data)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &out)
+	return
+}
+
+// encryptExport - builds password protected export with pbkdf2 key derivation
+func encryptExport(data []byte, password string, iterations int) (out Export, err error) {
+	var salt = make([]byte, 16)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return
+	}
+
+	out = Export{
+		Encrypted:         true,
+		PasswordProtected: true,
+		Salt:              base64.StdEncoding.EncodeToString(salt),
+		KdfType:           KdfTypePBKDF2,
+		KdfIterations:     iterations,
+	}
+
+	key, err := deriveKey(password, out.Salt, out.KdfType, out.KdfIterations, 0, 0)
+	if err != nil {
+		return
+	}
+
+	// any value works for validation, clients encrypt random guid
+	out.EncKeyValidation, err = key.encryptString([]byte(newUUID(out.Salt)))
+	if err != nil {
+		return
+	}
+
+	out.Data, err = key.encryptString(data)
+	return
+}
+
+func (k symmetricKey) sign(iv, data []byte) []byte {
+	var mac = hmac.New(sha256.New, k.mac)
+	mac.Write(iv)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
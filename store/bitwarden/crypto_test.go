@@ -0,0 +1,120 @@
+package bitwarden
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+//go:generate go run testdata/genexport.go
+
+const testExportPassword = "enpass2gopass-test"
+
+func readExport(t *testing.T, p string) Export {
+	t.Helper()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var e Export
+	if err = json.Unmarshal(b, &e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestDecryptExport(t *testing.T) {
+	var plain = readExport(t, "testdata/plain.json")
+	for _, p := range []string{"testdata/encrypted_pbkdf2.json", "testdata/encrypted_argon2.json"} {
+		var e = readExport(t, p)
+		if !e.Encrypted || !e.PasswordProtected {
+			t.Fatalf("%s: fixture is not password protected", p)
+		}
+
+		out, err := decryptExport(e, testExportPassword)
+		if err != nil {
+			t.Fatalf("%s: %s", p, err)
+		}
+
+		if len(out.Items) != len(plain.Items) || len(out.Folders) != len(plain.Folders) {
+			t.Fatalf("%s: decrypted export does not match plain one", p)
+		}
+		if v := str(out.Items[0].Login.Password); v != "s3cr3t" {
+			t.Errorf("%s: unexpected password %q", p, v)
+		}
+
+		if _, err = decryptExport(e, "wrong"); err != ErrInvalidPassword {
+			t.Errorf("%s: expected invalid password error, got %v", p, err)
+		}
+	}
+}
+
+func TestDecryptStringTampered(t *testing.T) {
+	var e = readExport(t, "testdata/encrypted_pbkdf2.json")
+	key, err := deriveKey(testExportPassword, e.Salt, e.KdfType, e.KdfIterations, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parts = strings.Split(e.Data, "|")
+	for name, s := range map[string]string{
+		"type":   "0" + e.Data[1:],
+		"parts":  parts[0] + "|" + parts[1],
+		"base64": parts[0] + "|!" + parts[1] + "|" + parts[2],
+		"mac":    parts[0] + "|" + parts[1] + "|" + strings.Repeat("A", len(parts[2])-1) + "=",
+	} {
+		if _, err = key.decryptString(s); err == nil {
+			t.Errorf("%s: tampered cipher string is decrypted", name)
+		}
+	}
+}
+
+func TestDeriveKeyInvalidParameters(t *testing.T) {
+	for _, tt := range []struct{ kdf, iterations, memory, parallelism int }{
+		{kdf: KdfTypePBKDF2},
+		{kdf: KdfTypeArgon2id, iterations: 3},
+		{kdf: 7, iterations: 1},
+	} {
+		if _, err := deriveKey("p", "s", tt.kdf, tt.iterations, tt.memory, tt.parallelism); err == nil {
+			t.Errorf("kdf %d with %+v is accepted", tt.kdf, tt)
+		}
+	}
+}
+
+func TestEncryptExport(t *testing.T) {
+	var data = []byte(`{"encrypted":false,"items":[{"id":"1","type":2,"name":"note"}]}`)
+	e, err := encryptExport(data, testExportPassword, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := decryptExport(e, testExportPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Items) != 1 || out.Items[0].Name != "note" {
+		t.Errorf("unexpected items %+v", out.Items)
+	}
+}
+
+func TestIdentityTitleField(t *testing.T) {
+	var plain = readExport(t, "testdata/plain.json")
+	fields, err := SourceItem{Item: plain.Items[1]}.GetFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values = make(map[string]string)
+	for _, f := range fields {
+		if _, ok := values[f.GetKey()]; ok {
+			t.Errorf("duplicated field %s", f.GetKey())
+		}
+		values[f.GetKey()] = f.GetValueString()
+	}
+
+	if values["title"] != "Passport" || values["identity_title"] != "Dr" {
+		t.Errorf("unexpected title fields: %v", values)
+	}
+}
@@ -0,0 +1,143 @@
+package bitwarden
+
+const (
+	// ItemTypeLogin -
+	ItemTypeLogin = 1
+	// ItemTypeSecureNote -
+	ItemTypeSecureNote = 2
+	// ItemTypeCard -
+	ItemTypeCard = 3
+	// ItemTypeIdentity -
+	ItemTypeIdentity = 4
+
+	// FieldTypeText -
+	FieldTypeText = 0
+	// FieldTypeHidden -
+	FieldTypeHidden = 1
+	// FieldTypeBoolean -
+	FieldTypeBoolean = 2
+	// FieldTypeLinked -
+	FieldTypeLinked = 3
+
+	// KdfTypePBKDF2 -
+	KdfTypePBKDF2 = 0
+	// KdfTypeArgon2id -
+	KdfTypeArgon2id = 1
+)
+
+// Export - bitwarden json export, either plain or password protected
+type Export struct {
+	Encrypted         bool `json:"encrypted"`
+	PasswordProtected bool `json:"passwordProtected,omitempty"`
+
+	// password protected export
+	Salt             string `json:"salt,omitempty"`
+	KdfType          int    `json:"kdfType"`
+	KdfIterations    int    `json:"kdfIterations,omitempty"`
+	KdfMemory        int    `json:"kdfMemory,omitempty"`
+	KdfParallelism   int    `json:"kdfParallelism,omitempty"`
+	EncKeyValidation string `json:"encKeyValidation_DO_NOT_EDIT,omitempty"`
+	Data             string `json:"data,omitempty"`
+
+	Folders []Folder `json:"folders,omitempty"`
+	Items   []Item   `json:"items,omitempty"`
+}
+
+// Folder -
+type Folder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Item -
+type Item struct {
+	ID             string      `json:"id"`
+	OrganizationID *string     `json:"organizationId"`
+	FolderID       *string     `json:"folderId"`
+	Type           int         `json:"type"`
+	Reprompt       int         `json:"reprompt"`
+	Name           string      `json:"name"`
+	Notes          *string     `json:"notes"`
+	Favorite       bool        `json:"favorite"`
+	Fields         []ItemField `json:"fields,omitempty"`
+	Login          *Login      `json:"login,omitempty"`
+	SecureNote     *SecureNote `json:"secureNote,omitempty"`
+	Card           *Card       `json:"card,omitempty"`
+	Identity       *Identity   `json:"identity,omitempty"`
+	CollectionIds  []string    `json:"collectionIds"`
+}
+
+// ItemField - custom field
+type ItemField struct {
+	Name     string  `json:"name"`
+	Value    *string `json:"value"`
+	Type     int     `json:"type"`
+	LinkedID *int    `json:"linkedId"`
+}
+
+// Login -
+type Login struct {
+	Uris     []LoginURI `json:"uris,omitempty"`
+	Username *string    `json:"username"`
+	Password *string    `json:"password"`
+	Totp     *string    `json:"totp"`
+}
+
+// LoginURI -
+type LoginURI struct {
+	Match *int   `json:"match"`
+	URI   string `json:"uri"`
+}
+
+// SecureNote -
+type SecureNote struct {
+	Type int `json:"type"`
+}
+
+// Card -
+type Card struct {
+	CardholderName *string `json:"cardholderName"`
+	Brand          *string `json:"brand"`
+	Number         *string `json:"number"`
+	ExpMonth       *string `json:"expMonth"`
+	ExpYear        *string `json:"expYear"`
+	Code           *string `json:"code"`
+}
+
+// Identity -
+type Identity struct {
+	Title          *string `json:"title"`
+	FirstName      *string `json:"firstName"`
+	MiddleName     *string `json:"middleName"`
+	LastName       *string `json:"lastName"`
+	Address1       *string `json:"address1"`
+	Address2       *string `json:"address2"`
+	Address3       *string `json:"address3"`
+	City           *string `json:"city"`
+	State          *string `json:"state"`
+	PostalCode     *string `json:"postalCode"`
+	Country        *string `json:"country"`
+	Company        *string `json:"company"`
+	Email          *string `json:"email"`
+	Phone          *string `json:"phone"`
+	SSN            *string `json:"ssn"`
+	Username       *string `json:"username"`
+	PassportNumber *string `json:"passportNumber"`
+	LicenseNumber  *string `json:"licenseNumber"`
+}
+
+// GetFoldersMap -
+func (e Export) GetFoldersMap() map[string]string {
+	out := make(map[string]string)
+	for _, f := range e.Folders {
+		out[f.ID] = f.Name
+	}
+	return out
+}
+
+func str(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
@@ -0,0 +1,472 @@
+package bitwarden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultKdfIterations - pbkdf2 iterations of password protected exports, same as bitwarden clients
+const DefaultKdfIterations = 600000
+
+// categories - item categories which can appear in secret path
+var categories = []string{"login", "note", "creditcard", "identity"}
+
+// plainExport - unencrypted export without password protection attributes
+type plainExport struct {
+	Encrypted bool     `json:"encrypted"`
+	Folders   []Folder `json:"folders"`
+	Items     []Item   `json:"items"`
+}
+
+// JsonStore - writes items into bitwarden json export file
+type JsonStore struct {
+	path          string
+	password      string
+	kdfIterations int
+	existing      map[string]Item
+	foldersCount  int
+	folders       map[string]string
+	data          plainExport
+	items         *utils.UniqueStrings
+	changed       bool
+	dryrun        bool
+	logger        *logrus.Logger
+}
+
+// newUUID - returns uuid-formatted hash of seed, so repeated exports keep item identities
+func newUUID(seed string) string {
+	var h = utils.GetHash(seed)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+func strPtr(v string) *string {
+	return &v
+}
+
+// folderID - returns id of folder, creates it if missing
+func (st *JsonStore) folderID(name string) string {
+	id, ok := st.folders[name]
+	if !ok {
+		id = newUUID("folder/" + name)
+		st.folders[name] = id
+	}
+
+	for _, f := range st.data.Folders {
+		if f.ID == id {
+			return id
+		}
+	}
+
+	st.data.Folders = append(st.data.Folders, Folder{ID: id, Name: name})
+	return id
+}
+
+// setCardField -
+func setCardField(c *Card, k, v string) bool {
+	var dst **string
+	switch k {
+	case "cardholder_name", "cardholder":
+		dst = &c.CardholderName
+	case "brand", "type":
+		dst = &c.Brand
+	case "number":
+		dst = &c.Number
+	case "exp_month":
+		dst = &c.ExpMonth
+	case "exp_year":
+		dst = &c.ExpYear
+	case "code", "cvc", "cvv":
+		dst = &c.Code
+	default:
+		return false
+	}
+	if *dst != nil {
+		return false
+	}
+	*dst = strPtr(v)
+	return true
+}
+
+// setIdentityField -
+func setIdentityField(id *Identity, k, v string) bool {
+	var dst **string
+	switch k {
+	case "identity_title", "title":
+		dst = &id.Title
+	case "first_name":
+		dst = &id.FirstName
+	case "middle_name":
+		dst = &id.MiddleName
+	case "last_name":
+		dst = &id.LastName
+	case "company":
+		dst = &id.Company
+	case "email":
+		dst = &id.Email
+	case "phone":
+		dst = &id.Phone
+	case "address1", "address":
+		dst = &id.Address1
+	case "address2":
+		dst = &id.Address2
+	case "address3":
+		dst = &id.Address3
+	case "city":
+		dst = &id.City
+	case "state":
+		dst = &id.State
+	case "postal_code", "zip":
+		dst = &id.PostalCode
+	case "country":
+		dst = &id.Country
+	case "ssn":
+		dst = &id.SSN
+	case "passport_number":
+		dst = &id.PassportNumber
+	case "license_number":
+		dst = &id.LicenseNumber
+	default:
+		return false
+	}
+	if *dst != nil {
+		return false
+	}
+	*dst = strPtr(v)
+	return true
+}
+
+// Save -
+func (st *JsonStore) Save(fields []field.FieldInterface, p string) (bool, error) {
+	p = st.items.Unique(p)
+	var levels []string
+	for _, level := range strings.Split(filepath.ToSlash(p), "/") {
+		if level != "" {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		return false, fmt.Errorf("invalid secret path: '%s'", p)
+	}
+
+	var item = Item{
+		Name:   levels[len(levels)-1],
+		Fields: []ItemField{},
+	}
+	levels = levels[:len(levels)-1]
+
+	// bitwarden has no archive or trash in exports, they are kept as folders
+	if len(levels) > 0 && levels[0] == "favorite" {
+		item.Favorite, levels = true, levels[1:]
+	}
+
+	var category string
+	for _, f := range fields {
+		if f.GetKey() == "category" && f.IsType(field.SecretSimpleField) {
+			category = f.GetValueString()
+			break
+		}
+	}
+
+	var folder []string
+	if len(levels) > 0 && (levels[0] == "archive" || levels[0] == "trash") {
+		folder, levels = append(folder, levels[0]), levels[1:]
+	}
+	if len(levels) > 0 && (levels[0] == category || category == "" && utils.InList(categories, levels[0])) {
+		category, levels = levels[0], levels[1:]
+	}
+	folder = append(folder, levels...)
+
+	var id string
+	var login = &Login{}
+	var card = &Card{}
+	var identity = &Identity{}
+	var hasLogin, hasCard, hasIdentity, hasTitle bool
+	var notes []string
+	var subtitle string
+	for _, f := range fields {
+		var k, v = f.GetKey(), f.GetValueString()
+		if v == "" {
+			continue
+		}
+
+		switch f.GetType() {
+		case field.SecretTitleField:
+			if !hasTitle {
+				item.Name, hasTitle = v, true
+				continue
+			}
+		case field.SecretIDField:
+			if k == "bitwarden_id" {
+				item.ID = v
+			} else if id == "" {
+				id = v
+			}
+			continue
+		case field.SecretAttachmentField:
+			st.logger.WithField("bitwardenkey", p).
+				Warnf("attachment '%s' is skipped, bitwarden json exports do not contain attachments", k)
+			continue
+		case field.SecretUsernameField:
+			if k == "subtitle" {
+				subtitle = v
+				continue
+			}
+			if login.Username == nil {
+				login.Username, hasLogin = strPtr(v), true
+				continue
+			}
+		case field.SecretTagsField:
+			// folder is exported as tags by most sources
+			var tags = strings.Trim(v, "[]")
+			if tags == "" || len(folder) > 0 && utils.Transliterate(tags) == folder[len(folder)-1] {
+				continue
+			}
+		case field.SecretPasswordField:
+			if login.Password == nil {
+				login.Password, hasLogin = strPtr(v), true
+				continue
+			}
+		case field.SecretURLField:
+			login.Uris, hasLogin = append(login.Uris, LoginURI{URI: v}), true
+			continue
+		}
+
+		switch {
+		case k == "category" && f.IsType(field.SecretSimpleField):
+			continue
+		case k == "username" && login.Username == nil:
+			login.Username, hasLogin = strPtr(v), true
+			continue
+		case (k == "totp" || f.IsType(field.SecretOTPField)) && login.Totp == nil:
+			login.Totp, hasLogin = strPtr(v), true
+			continue
+		case k == "note" && f.IsMultiline():
+			notes = append([]string{v}, notes...)
+			continue
+		case f.IsMultiline():
+			notes = append(notes, fmt.Sprintf("%s\n\n%s", k, v))
+			continue
+		case category == "creditcard" && setCardField(card, k, v):
+			hasCard = true
+			continue
+		case category == "identity" && setIdentityField(identity, k, v):
+			hasIdentity = true
+			continue
+		}
+
+		var fieldType = FieldTypeText
+		if f.IsSensitive() {
+			fieldType = FieldTypeHidden
+		}
+		item.Fields = append(item.Fields, ItemField{Name: k, Value: strPtr(v), Type: fieldType})
+	}
+
+	if login.Username == nil && subtitle != "" {
+		login.Username = strPtr(subtitle)
+	}
+	identity.Username = login.Username
+
+	switch {
+	case category == "creditcard" && hasCard:
+		item.Type, item.Card = ItemTypeCard, card
+	case category == "identity" && hasIdentity:
+		item.Type, item.Identity = ItemTypeIdentity, identity
+	case hasLogin:
+		item.Type, item.Login = ItemTypeLogin, login
+	default:
+		item.Type, item.SecureNote = ItemTypeSecureNote, &SecureNote{}
+	}
+
+	if len(notes) > 0 {
+		item.Notes = strPtr(strings.Join(notes, "\n"))
+	}
+
+	if len(folder) > 0 {
+		item.FolderID = strPtr(st.folderID(strings.Join(folder, "/")))
+	}
+
+	if item.ID == "" {
+		item.ID = newUUID(utils.FirstNonEmpty(id, p))
+	}
+
+	st.data.Items = append(st.data.Items, item)
+
+	var l = st.logger.WithField("bitwardenkey", p)
+	existing, ok := st.existing[item.ID]
+	delete(st.existing, item.ID)
+	if ok {
+		a, _ := json.Marshal(existing)
+		b, _ := json.Marshal(item)
+		if bytes.Equal(a, b) {
+			l.Debug("bitwarden item already in actual state")
+			return false, nil
+		}
+	}
+
+	l.Info("secret will be updated")
+	st.changed = true
+	return true, nil
+}
+
+// Cleanup - items of existing file which were not saved during this run are not written back
+func (st *JsonStore) Cleanup() (bool, error) {
+	for _, item := range st.existing {
+		st.logger.WithField("type", "cleaner").
+			WithField("bitwardenkey", item.Name).
+			Info("bitwarden item will be deleted")
+		st.changed = true
+	}
+
+	// folders of deleted items disappear from export
+	if len(st.data.Folders) != st.foldersCount {
+		st.changed = true
+	}
+
+	return len(st.existing) > 0, nil
+}
+
+// Close - writes json file
+func (st *JsonStore) Close() error {
+	if st.dryrun || !st.changed {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(st.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if st.password != "" {
+		e, err := encryptExport(data, st.password, st.kdfIterations)
+		if err != nil {
+			return fmt.Errorf("cannot encrypt bitwarden export: %s", err.Error())
+		}
+
+		data, err = json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = st.write(data)
+	if err != nil {
+		return fmt.Errorf("cannot write bitwarden json '%s': %s", st.path, err.Error())
+	}
+
+	st.changed = false
+	st.logger.WithField("path", st.path).Info("bitwarden json has been written")
+	return nil
+}
+
+// write - writes data into unique temporary file next to the target and
+// atomically replaces it, temporary file is readable by owner only
+func (st *JsonStore) write(data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(st.path), "."+filepath.Base(st.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+
+	var tmpPath = tmp.Name()
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		return
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		return
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return
+	}
+
+	return os.Rename(tmpPath, st.path)
+}
+
+// NewJsonStore - export is password protected if password is not empty;
+// existing file is used to keep folder ids and to detect changes
+func NewJsonStore(dataPath, password string, kdfIterations int, dryrun bool, logger *logrus.Logger) (st *JsonStore, err error) {
+	absPath, err := filepath.Abs(dataPath)
+	if err != nil {
+		return
+	}
+
+	if kdfIterations <= 0 {
+		kdfIterations = DefaultKdfIterations
+	}
+
+	st = &JsonStore{
+		path:          absPath,
+		password:      password,
+		kdfIterations: kdfIterations,
+		existing:      make(map[string]Item),
+		folders:       make(map[string]string),
+		data:          plainExport{Folders: []Folder{}, Items: []Item{}},
+		items:         utils.NewUniqueStrings(logger),
+		dryrun:        dryrun,
+		logger:        logger,
+	}
+
+	b, err := os.ReadFile(absPath)
+	switch {
+	case os.IsNotExist(err):
+		st.changed = true
+		return st, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var e Export
+	err = json.Unmarshal(b, &e)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse bitwarden json '%s': %s", absPath, err.Error())
+	}
+
+	// encryption mode change requires rewriting the file
+	if e.Encrypted != (password != "") {
+		st.changed = true
+	}
+
+	if e.Encrypted {
+		if !e.PasswordProtected || password == "" {
+			logger.WithField("path", absPath).Warn("existing encrypted bitwarden export cannot be read and will be overwritten")
+			return st, nil
+		}
+
+		e, err = decryptExport(e, password)
+		if errors.Is(err, ErrInvalidPassword) {
+			return nil, fmt.Errorf("existing bitwarden export '%s' is encrypted with another password", absPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range e.Folders {
+		st.folders[f.Name] = f.ID
+	}
+	st.foldersCount = len(e.Folders)
+	for _, item := range e.Items {
+		st.existing[item.ID] = item
+	}
+
+	return st, nil
+}
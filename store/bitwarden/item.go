@@ -0,0 +1,170 @@
+package bitwarden
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+// SourceItem - bitwarden item with resolved folder name
+type SourceItem struct {
+	Item
+	folder string
+}
+
+// GetCategoryPath -
+func (i SourceItem) GetCategoryPath() string {
+	switch i.Type {
+	case ItemTypeLogin:
+		return "login"
+	case ItemTypeSecureNote:
+		return "note"
+	case ItemTypeCard:
+		return "creditcard"
+	case ItemTypeIdentity:
+		return "identity"
+	}
+	return ""
+}
+
+// GetSecretPath -
+func (i SourceItem) GetSecretPath() (out string, err error) {
+	if i.Favorite {
+		out = filepath.Join(out, "favorite")
+	}
+
+	var cat = i.GetCategoryPath()
+	if cat == "" {
+		return "", fmt.Errorf("unsupported item type: %d", i.Type)
+	}
+	out = filepath.Join(out, cat)
+
+	if folder := utils.Transliterate(i.folder); folder != "" {
+		out = filepath.Join(out, folder)
+	}
+
+	var title = utils.Transliterate(i.Name)
+	if title == "" {
+		return "", errors.New("title cannot be empty")
+	}
+
+	return filepath.Join(out, title), nil
+}
+
+// simpleFields - appends non-empty values as simple fields
+func simpleFields(out []field.FieldInterface, sensitive []string, kv ...interface{}) []field.FieldInterface {
+	for n := 0; n+1 < len(kv); n += 2 {
+		var k = kv[n].(string)
+		var v = str(kv[n+1].(*string))
+		if v == "" {
+			continue
+		}
+		out = append(out, field.NewSimpleField(k, []byte(v), false, utils.InList(sensitive, k)))
+	}
+	return out
+}
+
+// GetFields -
+func (i SourceItem) GetFields() (out []field.FieldInterface, err error) {
+	if i.Name != "" {
+		out = append(out, field.NewTitleField("", i.Name))
+	}
+
+	if i.ID != "" {
+		out = append(out, field.NewIDField("bitwarden_id", i.ID))
+	}
+
+	if v := i.GetCategoryPath(); v != "" {
+		out = append(out, field.NewSimpleField("category", []byte(v), false, false))
+	}
+
+	if l := i.Login; l != nil {
+		if v := str(l.Username); v != "" {
+			out = append(out, field.NewUsernameField("", v))
+		}
+		if v := str(l.Password); v != "" {
+			out = append(out, field.NewPasswordField("", v))
+		}
+		for n, u := range l.Uris {
+			if u.URI == "" {
+				continue
+			}
+			var k = "url"
+			if n > 0 {
+				k = fmt.Sprintf("url_%d", n+1)
+			}
+			out = append(out, field.NewUrlField(k, u.URI))
+		}
+		if v := str(l.Totp); v != "" {
+			var rawURL string
+			if len(l.Uris) > 0 {
+				rawURL = l.Uris[0].URI
+			}
+			if uri, ok := utils.OTPAuthURI(v, utils.OTPIssuer(i.Name, rawURL), str(l.Username)); ok {
+				out = append(out, field.NewOTPField("", uri))
+			} else {
+				out = append(out, field.NewSimpleField("totp", []byte(v), false, true))
+			}
+		}
+	}
+
+	if c := i.Card; c != nil {
+		out = simpleFields(out, []string{"number", "code"},
+			"cardholder_name", c.CardholderName,
+			"brand", c.Brand,
+			"number", c.Number,
+			"exp_month", c.ExpMonth,
+			"exp_year", c.ExpYear,
+			"code", c.Code,
+		)
+	}
+
+	if id := i.Identity; id != nil {
+		if v := str(id.Username); v != "" {
+			out = append(out, field.NewUsernameField("", v))
+		}
+		out = simpleFields(out, []string{"ssn", "passport_number", "license_number"},
+			"identity_title", id.Title,
+			"first_name", id.FirstName,
+			"middle_name", id.MiddleName,
+			"last_name", id.LastName,
+			"company", id.Company,
+			"email", id.Email,
+			"phone", id.Phone,
+			"address1", id.Address1,
+			"address2", id.Address2,
+			"address3", id.Address3,
+			"city", id.City,
+			"state", id.State,
+			"postal_code", id.PostalCode,
+			"country", id.Country,
+			"ssn", id.SSN,
+			"passport_number", id.PassportNumber,
+			"license_number", id.LicenseNumber,
+		)
+	}
+
+	if v := str(i.Notes); v != "" {
+		out = append(out, field.NewSimpleField("note", []byte(v), true, false))
+	}
+
+	if i.folder != "" {
+		out = append(out, field.NewTagsField("", fmt.Sprintf("[%s]", i.folder)))
+	}
+
+	for _, f := range i.Fields {
+		var label = utils.Transliterate(f.Name)
+		var v = str(f.Value)
+		if f.Type == FieldTypeLinked || label == "" || v == "" {
+			continue
+		}
+
+		ff := field.NewSimpleField(label, []byte(v), false, f.Type == FieldTypeHidden)
+		out = append(out, ff)
+	}
+
+	return
+}
@@ -0,0 +1,74 @@
+package bitwarden
+
+import (
+	"errors"
+
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+const (
+	// SourceName -
+	SourceName = "bitwardenJsonSource"
+	// DestinationName -
+	DestinationName = "bitwardenJsonDestination"
+)
+
+func init() {
+	store.RegisterSource(store.SourceProvider{
+		Provider: store.Provider{
+			Name:        SourceName,
+			Description: "Bitwarden JSON export, plain or password protected",
+			FlagPrefix:  "source-bitwarden",
+			Options: []store.Option{
+				store.StringOption("json-path", "", "source bitwarden json export path").Require(),
+				store.StringOption("password-env", "BITWARDEN_EXPORT_PASSWORD", "environment variable with source bitwarden export password"),
+				store.StringOption("password-file", "", "file with source bitwarden export password"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreSource, error) {
+			s, err := NewBitwardenJsonSource(opts.String("json-path"), func() (string, error) {
+				password, _, err := utils.ReadPassword(opts.String("password-env"), opts.String("password-file"), true, "Bitwarden export password: ")
+				return password, err
+			})
+			if err != nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	})
+
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        DestinationName,
+			Description: "Bitwarden JSON export, optionally password protected",
+			FlagPrefix:  "destination-bitwarden",
+			Options: []store.Option{
+				store.StringOption("json-path", "", "destination bitwarden json export path").Require(),
+				store.BoolOption("encrypt", false, "write password protected bitwarden export"),
+				store.StringOption("password-env", "BITWARDEN_DESTINATION_PASSWORD", "environment variable with destination bitwarden export password"),
+				store.StringOption("password-file", "", "file with destination bitwarden export password"),
+				store.IntOption("kdf-iterations", DefaultKdfIterations, "pbkdf2 iterations of password protected bitwarden export"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			var password string
+			if opts.Bool("encrypt") {
+				var err error
+				password, _, err = utils.ReadPassword(opts.String("password-env"), opts.String("password-file"), true, "Bitwarden export password: ")
+				if err != nil {
+					return nil, err
+				}
+				if password == "" {
+					return nil, errors.New("destination bitwarden export password cannot be empty")
+				}
+			}
+
+			st, err := NewJsonStore(opts.String("json-path"), password, opts.Int("kdf-iterations"), env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
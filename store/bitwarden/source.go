@@ -0,0 +1,87 @@
+package bitwarden
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/revengel/enpass2gopass/store"
+)
+
+// PasswordFunc - returns export password, called only for password protected exports
+type PasswordFunc func() (string, error)
+
+// BitwardenSource -
+type BitwardenSource struct {
+	path     string
+	password PasswordFunc
+}
+
+// decrypt - decrypts password protected export
+func (s BitwardenSource) decrypt(e Export) (out Export, err error) {
+	if !e.PasswordProtected {
+		return out, errors.New("account encrypted exports are not supported, use plain or password protected export")
+	}
+
+	password, err := s.password()
+	if err != nil {
+		return
+	}
+
+	return decryptExport(e, password)
+}
+
+// LoadData -
+func (s BitwardenSource) LoadData() (o []store.StoreSourceItem, err error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+
+	var e Export
+	err = json.Unmarshal(b, &e)
+	if err != nil {
+		return
+	}
+
+	if e.Encrypted {
+		e, err = s.decrypt(e)
+		if err != nil {
+			return
+		}
+	}
+
+	var folders = e.GetFoldersMap()
+	for _, item := range e.Items {
+		var folder string
+		if item.FolderID != nil {
+			folder = folders[*item.FolderID]
+		}
+
+		o = append(o, SourceItem{
+			Item:   item,
+			folder: folder,
+		})
+	}
+
+	return o, nil
+}
+
+// GetSourcePath -
+func (s BitwardenSource) GetSourcePath() string {
+	return s.path
+}
+
+// NewBitwardenJsonSource -
+func NewBitwardenJsonSource(dataPath string, password PasswordFunc) (o *BitwardenSource, err error) {
+	absPath, err := filepath.Abs(dataPath)
+	if err != nil {
+		return
+	}
+
+	return &BitwardenSource{
+		path:     absPath,
+		password: password,
+	}, nil
+}
@@ -0,0 +1,100 @@
+//go:build ignore
+
+// genexport - generates password protected Bitwarden exports of plain.json
+// used by crypto tests; run with `go generate ./store/bitwarden`
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const password = "enpass2gopass-test"
+
+type export struct {
+	Encrypted         bool   `json:"encrypted"`
+	PasswordProtected bool   `json:"passwordProtected"`
+	Salt              string `json:"salt"`
+	KdfType           int    `json:"kdfType"`
+	KdfIterations     int    `json:"kdfIterations"`
+	KdfMemory         int    `json:"kdfMemory,omitempty"`
+	KdfParallelism    int    `json:"kdfParallelism,omitempty"`
+	EncKeyValidation  string `json:"encKeyValidation_DO_NOT_EDIT"`
+	Data              string `json:"data"`
+}
+
+func expand(master []byte, info string) []byte {
+	var out = make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, master, []byte(info)), out); err != nil {
+		log.Fatal(err)
+	}
+	return out
+}
+
+// encrypt - "2.iv|data|mac" cipher string, iv is derived from label to keep fixtures stable
+func encrypt(master []byte, label string, data []byte) string {
+	var enc, mac = expand(master, "enc"), expand(master, "mac")
+	var ivSum = sha256.Sum256([]byte(label))
+	var iv = ivSum[:aes.BlockSize]
+
+	block, err := aes.NewCipher(enc)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var pad = aes.BlockSize - len(data)%aes.BlockSize
+	var out = append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, out)
+
+	var h = hmac.New(sha256.New, mac)
+	h.Write(iv)
+	h.Write(out)
+	return fmt.Sprintf("2.%s|%s|%s", base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(out), base64.StdEncoding.EncodeToString(h.Sum(nil)))
+}
+
+func write(p string, e export, master, data []byte) {
+	e.Encrypted, e.PasswordProtected = true, true
+	e.EncKeyValidation = encrypt(master, p+"validation", []byte("0f4f6a52-6c1f-4a4e-9d0e-3c1b7a2d5e6f"))
+	e.Data = encrypt(master, p+"data", data)
+
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile(p, append(b, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	data, err := os.ReadFile("testdata/plain.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var salt = base64.StdEncoding.EncodeToString([]byte("enpass2gopass-salt"))
+
+	var pbkdf2Export = export{Salt: salt, KdfType: 0, KdfIterations: 1000}
+	write("testdata/encrypted_pbkdf2.json", pbkdf2Export,
+		pbkdf2.Key([]byte(password), []byte(salt), pbkdf2Export.KdfIterations, 32, sha256.New), data)
+
+	// bitwarden clients hash salt before argon2, memory is set in MiB
+	var argon2Export = export{Salt: salt, KdfType: 1, KdfIterations: 3, KdfMemory: 16, KdfParallelism: 4}
+	var saltHash = sha256.Sum256([]byte(salt))
+	write("testdata/encrypted_argon2.json", argon2Export,
+		argon2.IDKey([]byte(password), saltHash[:], 3, 16*1024, 4, 32), data)
+}
@@ -0,0 +1,75 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// windows1252 - characters of 0x80-0x9f range, other bytes match latin1
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// decodeText - converts file content to utf-8
+func decodeText(data []byte, encoding string) ([]byte, error) {
+	switch strings.ReplaceAll(strings.ToLower(encoding), "_", "-") {
+	case "", "utf-8", "utf8":
+		data = bytes.TrimPrefix(data, utf8BOM)
+		if !utf8.Valid(data) {
+			return nil, errors.New("csv file is not valid utf-8, set mapping encoding")
+		}
+		return data, nil
+	case "utf-16", "utf16":
+		var order binary.ByteOrder = binary.LittleEndian
+		if bytes.HasPrefix(data, []byte{0xfe, 0xff}) {
+			order = binary.BigEndian
+		}
+		return decodeUTF16(data, order)
+	case "utf-16le", "utf16le":
+		return decodeUTF16(data, binary.LittleEndian)
+	case "utf-16be", "utf16be":
+		return decodeUTF16(data, binary.BigEndian)
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return decodeSingleByte(data, false), nil
+	case "windows-1252", "cp1252":
+		return decodeSingleByte(data, true), nil
+	}
+
+	return nil, fmt.Errorf("unsupported csv encoding: '%s'", encoding)
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, errors.New("utf-16 csv file has odd length")
+	}
+
+	var units = make([]uint16, 0, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+	if len(units) > 0 && units[0] == 0xfeff {
+		units = units[1:]
+	}
+
+	return []byte(string(utf16.Decode(units))), nil
+}
+
+func decodeSingleByte(data []byte, cp1252 bool) []byte {
+	var out = make([]rune, 0, len(data))
+	for _, b := range data {
+		if cp1252 && b >= 0x80 && b <= 0x9f {
+			out = append(out, windows1252[b-0x80])
+			continue
+		}
+		out = append(out, rune(b))
+	}
+	return []byte(string(out))
+}
@@ -0,0 +1,61 @@
+package csv
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16Bytes(s string, order binary.ByteOrder, bom bool) []byte {
+	var units = utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+
+	var out = make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(out[2*i:], u)
+	}
+	return out
+}
+
+func TestDecodeText(t *testing.T) {
+	const text = "name,пароль\nCafé,€\n"
+	var tests = []struct {
+		encoding string
+		data     []byte
+		want     string
+	}{
+		{encoding: "", data: []byte(text), want: text},
+		{encoding: "UTF-8", data: append([]byte{0xef, 0xbb, 0xbf}, text...), want: text},
+		{encoding: "utf-16", data: utf16Bytes(text, binary.LittleEndian, true), want: text},
+		{encoding: "utf-16", data: utf16Bytes(text, binary.BigEndian, true), want: text},
+		{encoding: "utf-16le", data: utf16Bytes(text, binary.LittleEndian, false), want: text},
+		{encoding: "UTF_16BE", data: utf16Bytes(text, binary.BigEndian, false), want: text},
+		{encoding: "latin1", data: []byte("Caf\xe9 \x80"), want: "Café \u0080"},
+		{encoding: "windows-1252", data: []byte("Caf\xe9 \x80\x99"), want: "Café €™"},
+	}
+
+	for _, tt := range tests {
+		out, err := decodeText(tt.data, tt.encoding)
+		if err != nil {
+			t.Errorf("%s: %s", tt.encoding, err)
+			continue
+		}
+		if string(out) != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.encoding, tt.want, out)
+		}
+	}
+}
+
+func TestDecodeTextErrors(t *testing.T) {
+	for encoding, data := range map[string][]byte{
+		"utf-8":    []byte("Caf\xe9"),
+		"utf-16le": []byte("abc"),
+		"koi8-r":   []byte("abc"),
+	} {
+		if _, err := decodeText(data, encoding); err == nil {
+			t.Errorf("%s: invalid input is decoded", encoding)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+// column - value of csv cell with its target
+type column struct {
+	name   string
+	target string
+	value  string
+}
+
+// SourceItem - single csv row
+type SourceItem struct {
+	columns []column
+	mapping Mapping
+}
+
+// values - returns non-empty values of the target
+func (i SourceItem) values(target string) (out []string) {
+	for _, c := range i.columns {
+		if c.target == target && c.value != "" {
+			out = append(out, c.value)
+		}
+	}
+	return
+}
+
+func (i SourceItem) value(target string) string {
+	if v := i.values(target); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// urlHost - host of first url
+func (i SourceItem) urlHost() string {
+	if u, err := url.Parse(i.value(TargetURL)); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+// GetTitle - returns title, falls back to url host and username
+func (i SourceItem) GetTitle() string {
+	if v := i.value(TargetTitle); v != "" {
+		return v
+	}
+
+	if v := i.urlHost(); v != "" {
+		return v
+	}
+
+	return i.value(TargetUsername)
+}
+
+// GetSecretPath -
+func (i SourceItem) GetSecretPath() (out string, err error) {
+	var folder = i.value(TargetFolder)
+	for _, level := range strings.Split(folder, i.mapping.folderSeparator()) {
+		if level = utils.Transliterate(level); level != "" {
+			out = filepath.Join(out, level)
+		}
+	}
+
+	var title = utils.Transliterate(i.GetTitle())
+	if title == "" {
+		return "", errors.New("title cannot be empty")
+	}
+	out = filepath.Join(out, title)
+
+	// presets without title column (firefox) share url host between
+	// accounts of the same site, username makes their paths unique
+	if i.value(TargetTitle) == "" && i.urlHost() != "" {
+		if username := utils.Transliterate(i.value(TargetUsername)); username != "" {
+			out = filepath.Join(out, username)
+		}
+	}
+
+	return out, nil
+}
+
+// GetFields -
+func (i SourceItem) GetFields() (out []field.FieldInterface, err error) {
+	if v := i.GetTitle(); v != "" {
+		out = append(out, field.NewTitleField("", v))
+	}
+
+	if v := i.value(TargetID); v != "" {
+		out = append(out, field.NewIDField("csv_id", v))
+	}
+
+	if v := i.value(TargetUsername); v != "" {
+		out = append(out, field.NewUsernameField("", v))
+	}
+
+	if v := i.value(TargetPassword); v != "" {
+		out = append(out, field.NewPasswordField("", v))
+	}
+
+	for n, v := range i.values(TargetURL) {
+		var k = "url"
+		if n > 0 {
+			k = fmt.Sprintf("url_%d", n+1)
+		}
+		out = append(out, field.NewUrlField(k, v))
+	}
+
+	var tags []string
+	for _, v := range i.values(TargetTags) {
+		for _, t := range strings.Split(v, i.mapping.tagsSeparator()) {
+			if t = strings.TrimSpace(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+	}
+	if len(tags) > 0 {
+		out = append(out, field.NewTagsField("", fmt.Sprintf("[%s]", strings.Join(tags, ", "))))
+	}
+
+	for _, c := range i.columns {
+		switch c.target {
+		case TargetTitle, TargetUsername, TargetPassword, TargetURL, TargetNotes,
+			TargetTags, TargetFolder, TargetID, TargetSkip:
+			continue
+		}
+
+		if c.value == "" {
+			continue
+		}
+
+		if c.target == "totp" {
+			var issuer = utils.OTPIssuer(i.value(TargetTitle), i.value(TargetURL))
+			if uri, ok := utils.OTPAuthURI(c.value, issuer, i.value(TargetUsername)); ok {
+				out = append(out, field.NewOTPField("", uri))
+				continue
+			}
+		}
+
+		var multiline = strings.Contains(c.value, "\n")
+		var sensitive = utils.InList(i.mapping.Sensitive, c.target)
+		out = append(out, field.NewSimpleField(c.target, []byte(c.value), multiline, sensitive))
+	}
+
+	if notes := i.values(TargetNotes); len(notes) > 0 {
+		out = append(out, field.NewSimpleField("note", []byte(strings.Join(notes, "\n")), true, false))
+	}
+
+	return
+}
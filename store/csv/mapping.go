@@ -0,0 +1,209 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// column targets, any other target is used as custom field key
+const (
+	TargetTitle    = "title"
+	TargetUsername = "username"
+	TargetPassword = "password"
+	TargetURL      = "url"
+	TargetNotes    = "notes"
+	TargetTags     = "tags"
+	TargetFolder   = "folder"
+	TargetID       = "id"
+	TargetSkip     = "skip"
+)
+
+// Mapping - describes csv file layout
+type Mapping struct {
+	// Delimiter - fields delimiter, "," by default
+	Delimiter string `yaml:"delimiter"`
+	// Header - first row contains column names, true by default;
+	// without header columns are referenced by 1-based index
+	Header *bool `yaml:"header"`
+	// Encoding - utf-8, utf-16, utf-16le, utf-16be, latin1 or windows-1252
+	Encoding string `yaml:"encoding"`
+	// Columns - column name to target
+	Columns map[string]string `yaml:"columns"`
+	// Sensitive - custom keys which have to be stored as sensitive
+	Sensitive []string `yaml:"sensitive"`
+	// TagsSeparator - separator of tags column values, "," by default
+	TagsSeparator string `yaml:"tags_separator"`
+	// FolderSeparator - separator of nested folders, "/" by default
+	FolderSeparator string `yaml:"folder_separator"`
+
+	// preset - built-in mapping, only its core columns are required in header
+	preset bool
+}
+
+// coreTargets - targets which every export of preset has, other preset columns
+// are absent in exports of older versions, e.g. chrome note or keepassxc TOTP
+var coreTargets = []string{TargetTitle, TargetUsername, TargetPassword, TargetURL}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+var presets = map[string]Mapping{
+	"chrome": {
+		Columns: map[string]string{
+			"name":     TargetTitle,
+			"url":      TargetURL,
+			"username": TargetUsername,
+			"password": TargetPassword,
+			"note":     TargetNotes,
+		},
+	},
+	"firefox": {
+		Columns: map[string]string{
+			"url":                 TargetURL,
+			"username":            TargetUsername,
+			"password":            TargetPassword,
+			"httpRealm":           TargetSkip,
+			"formActionOrigin":    TargetSkip,
+			"guid":                TargetID,
+			"timeCreated":         TargetSkip,
+			"timeLastUsed":        TargetSkip,
+			"timePasswordChanged": TargetSkip,
+		},
+	},
+	"keepassxc": {
+		Columns: map[string]string{
+			"Group":         TargetFolder,
+			"Title":         TargetTitle,
+			"Username":      TargetUsername,
+			"Password":      TargetPassword,
+			"URL":           TargetURL,
+			"Notes":         TargetNotes,
+			"TOTP":          "totp",
+			"Icon":          TargetSkip,
+			"Last Modified": TargetSkip,
+			"Created":       TargetSkip,
+		},
+		Sensitive: []string{"totp"},
+	},
+	"lastpass": {
+		Columns: map[string]string{
+			"url":      TargetURL,
+			"username": TargetUsername,
+			"password": TargetPassword,
+			"totp":     "totp",
+			"extra":    TargetNotes,
+			"name":     TargetTitle,
+			"grouping": TargetFolder,
+			"fav":      TargetSkip,
+		},
+		Sensitive: []string{"totp"},
+	},
+}
+
+// Presets - returns names of built-in mappings
+func Presets() (out []string) {
+	for k := range presets {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return
+}
+
+// GetPreset -
+func GetPreset(name string) (Mapping, error) {
+	m, ok := presets[strings.ToLower(name)]
+	if !ok {
+		return Mapping{}, fmt.Errorf("unknown csv preset '%s', available: %s", name, strings.Join(Presets(), ", "))
+	}
+	m.preset = true
+	return m, nil
+}
+
+// LoadMapping - reads mapping from yaml (or json) file
+func LoadMapping(p string) (m Mapping, err error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return
+	}
+
+	err = yaml.Unmarshal(data, &m)
+	if err != nil {
+		return m, fmt.Errorf("cannot parse csv mapping '%s': %s", p, err.Error())
+	}
+
+	return m, m.validate()
+}
+
+func (m Mapping) hasHeader() bool {
+	return m.Header == nil || *m.Header
+}
+
+func (m Mapping) delimiter() rune {
+	if m.Delimiter == "" {
+		return ','
+	}
+	if m.Delimiter == `\t` {
+		return '\t'
+	}
+	r, _ := utf8.DecodeRuneInString(m.Delimiter)
+	return r
+}
+
+// required - reports whether column with target has to be present in header,
+// custom mapping requires every column which is not skipped
+func (m Mapping) required(target string) bool {
+	if target == TargetSkip {
+		return false
+	}
+	return !m.preset || slices.Contains(coreTargets, target)
+}
+
+func (m Mapping) tagsSeparator() string {
+	if m.TagsSeparator == "" {
+		return ","
+	}
+	return m.TagsSeparator
+}
+
+func (m Mapping) folderSeparator() string {
+	if m.FolderSeparator == "" {
+		return "/"
+	}
+	return m.FolderSeparator
+}
+
+func (m Mapping) validate() error {
+	if len(m.Columns) == 0 {
+		return errors.New("csv mapping has no columns")
+	}
+
+	if utf8.RuneCountInString(m.Delimiter) > 1 && m.Delimiter != `\t` {
+		return fmt.Errorf("csv delimiter must be a single character: '%s'", m.Delimiter)
+	}
+
+	if !m.hasHeader() {
+		for k := range m.Columns {
+			if n, err := strconv.Atoi(k); err != nil || n < 1 {
+				return fmt.Errorf("csv without header requires 1-based column indexes, got '%s'", k)
+			}
+		}
+	}
+
+	for k, v := range m.Columns {
+		if v == "" {
+			return fmt.Errorf("csv column '%s' has empty target", k)
+		}
+	}
+
+	_, err := decodeText(nil, m.Encoding)
+	return err
+}
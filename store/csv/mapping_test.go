@@ -0,0 +1,70 @@
+package csv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	for _, name := range Presets() {
+		m, err := GetPreset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = m.validate(); err != nil {
+			t.Errorf("preset %s is invalid: %s", name, err)
+		}
+	}
+
+	if _, err := GetPreset("Chrome"); err != nil {
+		t.Errorf("preset name is case sensitive: %s", err)
+	}
+	if _, err := GetPreset("opera"); err == nil {
+		t.Error("unknown preset is returned")
+	}
+}
+
+func TestMappingValidate(t *testing.T) {
+	var tests = map[string]Mapping{
+		"no columns":       {},
+		"long delimiter":   {Delimiter: ";;", Columns: map[string]string{"a": TargetTitle}},
+		"empty target":     {Columns: map[string]string{"a": ""}},
+		"named no header":  {Header: boolPtr(false), Columns: map[string]string{"a": TargetTitle}},
+		"zero index":       {Header: boolPtr(false), Columns: map[string]string{"0": TargetTitle}},
+		"unknown encoding": {Encoding: "koi8-r", Columns: map[string]string{"a": TargetTitle}},
+	}
+
+	for name, m := range tests {
+		if err := m.validate(); err == nil {
+			t.Errorf("%s: invalid mapping is accepted", name)
+		}
+	}
+
+	var m = Mapping{Delimiter: `\t`, Header: boolPtr(false), Columns: map[string]string{"1": TargetTitle}}
+	if err := m.validate(); err != nil {
+		t.Error(err)
+	}
+	if m.delimiter() != '\t' || m.hasHeader() {
+		t.Error("tab delimited mapping without header is parsed wrong")
+	}
+}
+
+func TestLoadMapping(t *testing.T) {
+	var p = filepath.Join(t.TempDir(), "mapping.yaml")
+	var data = "delimiter: \";\"\ntags_separator: \"|\"\ncolumns:\n  Name: title\n  Login: username\n  PIN: pin\nsensitive: [pin]\n"
+	if err := os.WriteFile(p, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadMapping(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.delimiter() != ';' || m.tagsSeparator() != "|" || m.folderSeparator() != "/" {
+		t.Errorf("unexpected mapping %+v", m)
+	}
+	if m.Columns["PIN"] != "pin" || len(m.Sensitive) != 1 {
+		t.Errorf("unexpected columns %v", m.Columns)
+	}
+}
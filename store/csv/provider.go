@@ -0,0 +1,50 @@
+package csv
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/store"
+)
+
+// SourceName -
+const SourceName = "csvSource"
+
+func init() {
+	store.RegisterSource(store.SourceProvider{
+		Provider: store.Provider{
+			Name:        SourceName,
+			Description: "CSV export of browser or password manager, columns are mapped by preset or mapping file",
+			FlagPrefix:  "source-csv",
+			Options: []store.Option{
+				store.StringOption("path", "", "source csv file path").Require(),
+				store.StringOption("mapping", "", "source csv column mapping file (yaml or json)"),
+				store.StringOption("preset", "", "source csv built-in mapping: "+strings.Join(Presets(), ", ")),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreSource, error) {
+			var mapping Mapping
+			var err error
+			var mappingPath, preset = opts.String("mapping"), opts.String("preset")
+			switch {
+			case mappingPath != "" && preset != "":
+				return nil, errors.New("source csv mapping and preset cannot be used together")
+			case mappingPath != "":
+				mapping, err = LoadMapping(mappingPath)
+			case preset != "":
+				mapping, err = GetPreset(preset)
+			default:
+				return nil, errors.New("source csv mapping or preset is not set")
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			s, err := NewCsvSource(opts.String("path"), mapping)
+			if err != nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	})
+}
@@ -0,0 +1,123 @@
+package csv
+
+import (
+	"bytes"
+	stdcsv "encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/store"
+)
+
+// CsvSource - reads csv export described by mapping
+type CsvSource struct {
+	path    string
+	mapping Mapping
+}
+
+// LoadData -
+func (s CsvSource) LoadData() (o []store.StoreSourceItem, err error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+
+	data, err := decodeText(raw, s.mapping.Encoding)
+	if err != nil {
+		return
+	}
+
+	r := stdcsv.NewReader(bytes.NewReader(data))
+	r.Comma = s.mapping.delimiter()
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse csv file '%s': %s", s.path, err.Error())
+	}
+
+	// targets - column index to mapping key
+	var targets = make(map[int]string)
+	if s.mapping.hasHeader() {
+		if len(records) == 0 {
+			return nil, nil
+		}
+
+		for n, name := range records[0] {
+			var k = strings.TrimSpace(name)
+			if _, ok := s.mapping.Columns[k]; ok {
+				targets[n] = k
+			}
+		}
+		records = records[1:]
+
+		for k, target := range s.mapping.Columns {
+			if !s.mapping.required(target) {
+				continue
+			}
+
+			var found bool
+			for _, v := range targets {
+				found = found || v == k
+			}
+			if !found {
+				return nil, fmt.Errorf("csv column '%s' is not found in header", k)
+			}
+		}
+	} else {
+		for k := range s.mapping.Columns {
+			n, _ := strconv.Atoi(k)
+			targets[n-1] = k
+		}
+	}
+
+	for _, record := range records {
+		// skip blank lines
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		var item = SourceItem{mapping: s.mapping}
+		for n, v := range record {
+			k, ok := targets[n]
+			if !ok {
+				continue
+			}
+			item.columns = append(item.columns, column{
+				name:   k,
+				target: s.mapping.Columns[k],
+				value:  v,
+			})
+		}
+		o = append(o, item)
+	}
+
+	return o, nil
+}
+
+// GetSourcePath -
+func (s CsvSource) GetSourcePath() string {
+	return s.path
+}
+
+// NewCsvSource -
+func NewCsvSource(dataPath string, mapping Mapping) (o *CsvSource, err error) {
+	absPath, err := filepath.Abs(dataPath)
+	if err != nil {
+		return
+	}
+
+	err = mapping.validate()
+	if err != nil {
+		return
+	}
+
+	return &CsvSource{
+		path:    absPath,
+		mapping: mapping,
+	}, nil
+}
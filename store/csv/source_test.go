@@ -0,0 +1,220 @@
+package csv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+)
+
+func loadCsv(t *testing.T, data string, m Mapping) ([]SourceItem, error) {
+	t.Helper()
+	var p = filepath.Join(t.TempDir(), "export.csv")
+	if err := os.WriteFile(p, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewCsvSource(p, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := s.LoadData()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []SourceItem
+	for _, item := range items {
+		out = append(out, item.(SourceItem))
+	}
+	return out, nil
+}
+
+func secretPaths(t *testing.T, items []SourceItem) (out []string) {
+	t.Helper()
+	for _, item := range items {
+		p, err := item.GetSecretPath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, filepath.ToSlash(p))
+	}
+	return
+}
+
+func TestFirefoxPresetPathsAreUnique(t *testing.T) {
+	const data = `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"
+"https://github.com","octocat","one",,"https://github.com","{1}","1","1","1"
+"https://github.com","hubot","two",,"https://github.com","{2}","1","1","1"
+"https://example.com","","three",,"","{3}","1","1","1"
+`
+	m, _ := GetPreset("firefox")
+	items, err := loadCsv(t, data, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths = secretPaths(t, items)
+	var want = []string{"github_com/octocat", "github_com/hubot", "example_com"}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("expected paths %v, got %v", want, paths)
+	}
+}
+
+func TestKeepassxcPreset(t *testing.T) {
+	const data = "\"Group\",\"Title\",\"Username\",\"Password\",\"URL\",\"Notes\",\"TOTP\",\"Icon\",\"Last Modified\",\"Created\"\n" +
+		"\"Root/Работа\",\"GitHub\",\"octocat\",\"s3cr3t\",\"https://github.com\",\"line 1\nline 2\",\"JBSWY3DPEHPK3PXP\",\"0\",\"\",\"\"\n"
+
+	m, _ := GetPreset("keepassxc")
+	items, err := loadCsv(t, data, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if paths := secretPaths(t, items); len(paths) != 1 || paths[0] != "root/rabota/github" {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+
+	fields, err := items[0].GetFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values = make(map[string]field.FieldInterface)
+	for _, f := range fields {
+		values[f.GetKey()] = f
+	}
+
+	for k, v := range map[string]string{
+		"title":    "GitHub",
+		"username": "octocat",
+		"password": "s3cr3t",
+		"url":      "https://github.com",
+		"note":     "line 1\nline 2",
+		"otpauth":  "otpauth://totp/GitHub:octocat?issuer=GitHub&secret=JBSWY3DPEHPK3PXP",
+	} {
+		if f, ok := values[k]; !ok || f.GetValueString() != v {
+			t.Errorf("field %s: expected %q, got %v", k, v, f)
+		}
+	}
+
+	if !values["note"].IsMultiline() || !values["url"].IsType(field.SecretURLField) {
+		t.Error("field types are mapped wrong")
+	}
+}
+
+func TestCustomMapping(t *testing.T) {
+	const data = "Site;1;secret;pin;a|b\nMail;2;pass;;\n"
+	var m = Mapping{
+		Delimiter: ";",
+		Header:    boolPtr(false),
+		Columns: map[string]string{
+			"1": TargetTitle,
+			"2": TargetID,
+			"3": TargetPassword,
+			"4": "pin",
+			"5": TargetTags,
+		},
+		Sensitive:     []string{"pin"},
+		TagsSeparator: "|",
+	}
+
+	items, err := loadCsv(t, data, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+
+	fields, err := items[0].GetFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values = make(map[string]field.FieldInterface)
+	for _, f := range fields {
+		values[f.GetKey()] = f
+	}
+
+	if values["csv_id"].GetValueString() != "1" || values["tags"].GetValueString() != "[a, b]" {
+		t.Errorf("unexpected fields %v", values)
+	}
+	if f := values["pin"]; f == nil || !f.IsSensitive() {
+		t.Errorf("custom sensitive field is mapped wrong: %v", f)
+	}
+}
+
+func TestMissingHeaderColumn(t *testing.T) {
+	var tests = []struct {
+		name   string
+		preset string
+		data   string
+		fields map[string]string
+		err    string
+	}{
+		{
+			name:   "chrome without note",
+			preset: "chrome",
+			data:   "name,url,username,password\nGitHub,https://github.com,octocat,s3cr3t\n",
+			fields: map[string]string{"title": "GitHub", "username": "octocat", "password": "s3cr3t"},
+		},
+		{
+			name:   "keepassxc without totp and icon",
+			preset: "keepassxc",
+			data:   "Group,Title,Username,Password,URL,Notes\nRoot,GitHub,octocat,s3cr3t,https://github.com,note\n",
+			fields: map[string]string{"title": "GitHub", "username": "octocat", "password": "s3cr3t"},
+		},
+		{
+			name:   "chrome without password",
+			preset: "chrome",
+			data:   "name,url,username,note\nGitHub,https://github.com,octocat,\n",
+			err:    "'password'",
+		},
+		{
+			name: "custom mapping",
+			data: "name,login\nGitHub,octocat\n",
+			err:  "'pin'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m = Mapping{Columns: map[string]string{"name": TargetTitle, "login": TargetUsername, "pin": "pin"}}
+			if tt.preset != "" {
+				m, _ = GetPreset(tt.preset)
+			}
+
+			items, err := loadCsv(t, tt.data, m)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected missing column %s error, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("expected 1 item, got %d", len(items))
+			}
+
+			fields, err := items[0].GetFields()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var values = make(map[string]string)
+			for _, f := range fields {
+				values[f.GetKey()] = f.GetValueString()
+			}
+			for k, v := range tt.fields {
+				if values[k] != v {
+					t.Errorf("field %s = %q, expected %q", k, values[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package dotenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+const (
+	// FormatDotenv - KEY=value lines of .env file
+	FormatDotenv = "dotenv"
+	// FormatShell - export KEY='value' lines to be sourced by posix shell
+	FormatShell = "shell"
+	// FormatSystemd - KEY=value lines of systemd EnvironmentFile
+	FormatSystemd = "systemd"
+)
+
+var (
+	// safeValueRe - values which do not need quoting in any format
+	safeValueRe = regexp.MustCompile(`^[-_./:@%+,=a-zA-Z0-9]*$`)
+	varNameRe   = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+// Formats - supported output formats
+func Formats() []string {
+	return []string{FormatDotenv, FormatShell, FormatSystemd}
+}
+
+// VarName - converts secret path or field key into UPPER_SNAKE variable name
+func VarName(parts ...string) string {
+	var names []string
+	for _, p := range parts {
+		var n = strings.Trim(varNameRe.ReplaceAllString(strings.ToUpper(utils.Transliterate(p)), "_"), "_")
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+
+	var out = strings.Join(names, "_")
+	if out != "" && out[0] >= '0' && out[0] <= '9' {
+		out = "_" + out
+	}
+	return out
+}
+
+// singleQuote - posix shell quoting, everything inside single quotes is literal
+func singleQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+// doubleQuote - escapes given characters with backslash, newline is written as \n if escapeNewline is set
+func doubleQuote(v, special string, escapeNewline bool) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range v {
+		switch {
+		case r == '\n' && escapeNewline:
+			b.WriteString(`\n`)
+		case strings.ContainsRune(special, r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// quote - quotes value for format; sensitive values are always quoted
+// to keep them intact whatever characters they contain
+func quote(format, v string, sensitive bool) string {
+	if !sensitive && safeValueRe.MatchString(v) {
+		return v
+	}
+
+	switch format {
+	case FormatShell:
+		return singleQuote(v)
+	case FormatSystemd:
+		// systemd keeps newlines of quoted value and unescapes backslash sequences
+		return doubleQuote(v, "\\\"`$", false)
+	}
+
+	// single quoted dotenv values are literal, but cannot contain quote and newline
+	if !strings.ContainsAny(v, "'\n\r") {
+		return "'" + v + "'"
+	}
+	return doubleQuote(strings.ReplaceAll(v, "\r", ""), "\\\"", true)
+}
+
+// line - variable assignment in format
+func line(format, name, v string, sensitive bool) string {
+	var assignment = fmt.Sprintf("%s=%s", name, quote(format, v, sensitive))
+	if format == FormatShell {
+		return "export " + assignment
+	}
+	return assignment
+}
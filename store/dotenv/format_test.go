@@ -0,0 +1,86 @@
+package dotenv
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestVarName(t *testing.T) {
+	for _, tc := range []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"web/site", "user name"}, "WEB_SITE_USER_NAME"},
+		{[]string{"", "--api-key--"}, "API_KEY"},
+		{[]string{"1password"}, "_1PASSWORD"},
+		{[]string{"web", "2fa"}, "WEB_2FA"},
+		{[]string{"Ключ API"}, "KLJUCH_API"},
+		// letters without transliteration become separators
+		{[]string{"naïve-key"}, "NA_VE_KEY"},
+		{[]string{"db", "日本"}, "DB"},
+		{[]string{"!!!"}, ""},
+	} {
+		if got := VarName(tc.parts...); got != tc.want {
+			t.Errorf("%q: expected %q, got %q", tc.parts, tc.want, got)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		value     string
+		sensitive bool
+		dotenv    string
+		shell     string
+		systemd   string
+	}{
+		{"safe", "a-b_c.d/e:f@g", false, `a-b_c.d/e:f@g`, `a-b_c.d/e:f@g`, `a-b_c.d/e:f@g`},
+		{"empty", "", false, ``, ``, ``},
+		{"sensitive", "secret", true, `'secret'`, `'secret'`, `"secret"`},
+		{"space", "a b", false, `'a b'`, `'a b'`, `"a b"`},
+		{"single quote", "it's", false, `"it's"`, `'it'\''s'`, `"it's"`},
+		{"double quote", `say "hi"`, false, `'say "hi"'`, `'say "hi"'`, `"say \"hi\""`},
+		{"dollar", "$HOME", false, `'$HOME'`, `'$HOME'`, `"\$HOME"`},
+		{"backtick", "`id`", false, "'`id`'", "'`id`'", "\"\\`id\\`\""},
+		{"backslash", `a\b`, false, `'a\b'`, `'a\b'`, `"a\\b"`},
+		{"multiline", "l1\nl2", false, `"l1\nl2"`, "'l1\nl2'", "\"l1\nl2\""},
+		{"crlf", "l1\r\nl2", false, `"l1\nl2"`, "'l1\r\nl2'", "\"l1\r\nl2\""},
+		{"mixed", "it's \"$x\"\\\nz", true, `"it's \"$x\"\\\nz"`, "'it'\\''s \"$x\"\\\nz'", "\"it's \\\"\\$x\\\"\\\\\nz\""},
+	} {
+		for format, want := range map[string]string{FormatDotenv: tc.dotenv, FormatShell: tc.shell, FormatSystemd: tc.systemd} {
+			if got := quote(format, tc.value, tc.sensitive); got != want {
+				t.Errorf("%s, %s: expected %s, got %s", tc.name, format, want, got)
+			}
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	for format, want := range map[string]string{
+		FormatDotenv:  `DB_PASSWORD='p@ss word'`,
+		FormatShell:   `export DB_PASSWORD='p@ss word'`,
+		FormatSystemd: `DB_PASSWORD="p@ss word"`,
+	} {
+		if got := line(format, "DB_PASSWORD", "p@ss word", true); got != want {
+			t.Errorf("%s: expected %s, got %s", format, want, got)
+		}
+	}
+}
+
+// TestShellLine - sourced line gives back the value as is
+func TestShellLine(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+
+	for _, v := range []string{"", "plain", "it's", `say "hi"`, "$HOME `id` $(id)", `a\b\n`, "l1\nl2\r\n", "пароль"} {
+		out, err := exec.Command("sh", "-c", line(FormatShell, "V", v, true)+`; printf '%s' "$V"`).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != v {
+			t.Errorf("expected %q, got %q", v, out)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package dotenv
+
+import (
+	"strings"
+
+	"github.com/revengel/enpass2gopass/store"
+)
+
+// DestinationName -
+const DestinationName = "envDestination"
+
+func init() {
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        DestinationName,
+			Description: "dotenv, shell export or systemd EnvironmentFile with fields of selected items",
+			FlagPrefix:  "destination-env",
+			Options: []store.Option{
+				store.StringOption("path", "", "destination env file path").Require(),
+				store.StringOption("format", FormatDotenv, "destination env file format: "+strings.Join(Formats(), ", ")),
+				store.StringOption("folder", "", "secret path of folder or item to export, variables of nested items are prefixed with their relative path"),
+				store.StringOption("var-prefix", "", "prefix of all exported variable names"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			st, err := NewStore(opts.String("path"), opts.String("format"), opts.String("folder"), opts.String("var-prefix"),
+				env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
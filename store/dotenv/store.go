@@ -0,0 +1,179 @@
+package dotenv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// skippedKeys - item metadata which is useless as environment variable
+var skippedKeys = []string{"category"}
+
+// Store - writes fields of items under folder as environment variables
+type Store struct {
+	path      string
+	format    string
+	folder    string
+	varPrefix string
+	names     map[string]bool
+	lines     []string
+	dryrun    bool
+	logger    *logrus.Logger
+}
+
+// itemPrefix - variable name prefix of item, it is empty if folder points to item itself
+func (st *Store) itemPrefix(p string) (string, bool) {
+	if st.folder == "" {
+		return p, true
+	}
+	if p == st.folder {
+		return "", true
+	}
+	if strings.HasPrefix(p, st.folder+"/") {
+		return strings.TrimPrefix(p, st.folder+"/"), true
+	}
+	return "", false
+}
+
+// uniqueName - appends index to already used variable name
+func (st *Store) uniqueName(name string) string {
+	var out = name
+	for i := 2; st.names[out]; i++ {
+		out = fmt.Sprintf("%s_%d", name, i)
+	}
+	st.names[out] = true
+	return out
+}
+
+// Save -
+func (st *Store) Save(fields []field.FieldInterface, p string) (bool, error) {
+	p = filepath.ToSlash(p)
+	itemPrefix, ok := st.itemPrefix(p)
+	if !ok {
+		st.logger.WithField("envkey", p).Debug("secret is not under env folder and is skipped")
+		return false, nil
+	}
+
+	var parts []string
+	if st.varPrefix != "" {
+		parts = append(parts, st.varPrefix)
+	}
+	if itemPrefix != "" {
+		parts = append(parts, strings.Split(itemPrefix, "/")...)
+	}
+
+	var out bool
+	for _, f := range fields {
+		switch {
+		case f.IsType(field.SecretAttachmentField), f.IsType(field.SecretTitleField),
+			f.IsType(field.SecretTagsField), f.IsType(field.SecretIDField):
+			continue
+		case utils.InList(skippedKeys, f.GetKey()), f.GetValueString() == "":
+			continue
+		}
+
+		var l = st.logger.WithField("envkey", path.Join(p, f.GetKey()))
+		// key without usable characters would give item prefix as variable name
+		var name = VarName(append(parts, f.GetKey())...)
+		if VarName(f.GetKey()) == "" {
+			l.Warn("field key cannot be used as variable name")
+			continue
+		}
+
+		// environment variables cannot hold binary data
+		if strings.ContainsRune(f.GetValueString(), 0) {
+			l.Warn("field value contains NUL byte and cannot be exported")
+			continue
+		}
+
+		var sensitive = f.IsSensitive() || f.IsType(field.SecretPasswordField)
+		st.lines = append(st.lines, line(st.format, st.uniqueName(name), f.GetValueString(), sensitive))
+		out = true
+	}
+
+	return out, nil
+}
+
+// Cleanup - file is always written from scratch
+func (st *Store) Cleanup() (bool, error) {
+	return false, nil
+}
+
+// Close - writes collected variables if they differ from file content
+func (st *Store) Close() error {
+	var l = st.logger.WithField("path", st.path)
+	if len(st.lines) == 0 {
+		l.Warn("there are no variables to write to env file")
+		return nil
+	}
+
+	var data = []byte(strings.Join(st.lines, "\n") + "\n")
+	current, err := os.ReadFile(st.path)
+	if err == nil && bytes.Equal(current, data) {
+		l.Debug("env file already in actual state")
+		return nil
+	}
+
+	l.Info("env file will be updated")
+	if st.dryrun {
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(st.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(st.path), "."+filepath.Base(st.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), st.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	l.Info("env file has been updated")
+	return nil
+}
+
+// NewStore - folder selects secrets by path, variables of nested items are prefixed
+// with item path relative to folder
+func NewStore(p, format, folder, varPrefix string, dryrun bool, logger *logrus.Logger) (*Store, error) {
+	if p == "" {
+		return nil, errors.New("destination env file is not set")
+	}
+
+	format = utils.FirstNonEmpty(format, FormatDotenv)
+	if !utils.InList(Formats(), format) {
+		return nil, fmt.Errorf("invalid env format: '%s'", format)
+	}
+
+	return &Store{
+		path:      p,
+		format:    format,
+		folder:    strings.Trim(filepath.ToSlash(folder), "/"),
+		varPrefix: varPrefix,
+		names:     make(map[string]bool),
+		dryrun:    dryrun,
+		logger:    logger,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package dotenv
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Logger {
+	var l = logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+func TestStoreSave(t *testing.T) {
+	var fields = []field.FieldInterface{
+		field.NewTitleField("", "Site"),
+		field.NewIDField("enpass_uuid", "0b3c7f6e"),
+		field.NewUsernameField("", "user"),
+		field.NewPasswordField("", "pass"),
+		field.NewSimpleField("token", []byte("abc"), false, true),
+		field.NewSimpleField("notes", []byte("l1\nl2"), true, false),
+		field.NewSimpleField("日本", []byte("skipped"), false, false),
+		field.NewAttachmentField("key.bin", []byte{0x00}),
+	}
+
+	for format, want := range map[string]string{
+		FormatDotenv:  "APP_DB_USERNAME=user\nAPP_DB_PASSWORD='pass'\nAPP_DB_TOKEN='abc'\nAPP_DB_NOTES=\"l1\\nl2\"\nAPP_DB_USERNAME_2=admin\n",
+		FormatShell:   "export APP_DB_USERNAME=user\nexport APP_DB_PASSWORD='pass'\nexport APP_DB_TOKEN='abc'\nexport APP_DB_NOTES='l1\nl2'\nexport APP_DB_USERNAME_2=admin\n",
+		FormatSystemd: "APP_DB_USERNAME=user\nAPP_DB_PASSWORD=\"pass\"\nAPP_DB_TOKEN=\"abc\"\nAPP_DB_NOTES=\"l1\nl2\"\nAPP_DB_USERNAME_2=admin\n",
+	} {
+		var p = filepath.Join(t.TempDir(), "app.env")
+		st, err := NewStore(p, format, "env/app", "app", false, testLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// item outside of folder is skipped, names of different items can collide
+		for _, item := range []struct {
+			path   string
+			fields []field.FieldInterface
+		}{
+			{"env/app/db", fields},
+			{"web/site", []field.FieldInterface{field.NewUsernameField("", "other")}},
+			{"env/app/db", []field.FieldInterface{field.NewUsernameField("", "admin")}},
+		} {
+			if _, err = st.Save(item.fields, item.path); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err = st.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", format, want, data)
+		}
+	}
+}
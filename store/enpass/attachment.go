@@ -24,13 +24,14 @@ func (a Attachment) GetDataBase64Encoded() string {
 func (a Attachment) GetDataBytes() (o []byte, err error) {
 	var datab64 = a.GetDataBase64Encoded()
 	var dataB = []byte(datab64)
-	var sizeDecoded = base64.StdEncoding.EncodedLen(len(datab64))
+	var sizeDecoded = base64.StdEncoding.DecodedLen(len(datab64))
 	o = make([]byte, sizeDecoded)
-	_, err = base64.StdEncoding.Decode(o, dataB)
+	n, err := base64.StdEncoding.Decode(o, dataB)
 	if err != nil {
-		return
+		return nil, err
 	}
-	return
+	// decoded length is upper bound, padding is not counted
+	return o[:n], nil
 }
 
 // GetDataString -
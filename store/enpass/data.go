@@ -1,6 +1,7 @@
 package enpass
 
 import (
+	"github.com/revengel/enpass2gopass/store"
 	"github.com/revengel/enpass2gopass/utils"
 )
 
@@ -24,3 +25,15 @@ func (d Data) GetFoldersMap() FoldersMap {
 	}
 	return out
 }
+
+// GetSourceItems - returns items with folder ids resolved to folder names
+func (d Data) GetSourceItems() []store.StoreSourceItem {
+	var foldersMap = d.GetFoldersMap()
+	var items []store.StoreSourceItem
+	for _, item := range d.Items {
+		var folders = foldersMap.GetFolders(item.Folders)
+		item.Folders = folders
+		items = append(items, item)
+	}
+	return items
+}
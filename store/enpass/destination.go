@@ -0,0 +1,327 @@
+package enpass
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// categories - enpass item categories which can appear in secret path
+var categories = []string{
+	"login", "creditcard", "note", "identity", "password", "finance",
+	"license", "travel", "computer", "misc", "document",
+}
+
+// fieldLabels - labels of well-known enpass fields
+var fieldLabels = map[string]string{
+	"username": "Username",
+	"password": "Password",
+	"url":      "Website",
+	"email":    "E-mail",
+	"totp":     "TOTP",
+	"otpauth":  "TOTP",
+}
+
+// JsonStore - writes items into enpass json export file
+type JsonStore struct {
+	path     string
+	existing map[string]DataItem
+	folders  map[string]string
+	data     Data
+	items    *utils.UniqueStrings
+	dryrun   bool
+	logger   *logrus.Logger
+}
+
+// newUUID - returns uuid-formatted hash of seed, so repeated exports keep item identities
+func newUUID(seed string) string {
+	var h = utils.GetHash(seed)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+// folderUUID - returns uuid of folder, creates it if missing
+func (st *JsonStore) folderUUID(title string) string {
+	if id, ok := st.folders[title]; ok {
+		for _, f := range st.data.Folders {
+			if f.UUID == id {
+				return id
+			}
+		}
+		st.data.Folders = append(st.data.Folders, FolderItem{UUID: id, Title: title})
+		return id
+	}
+
+	var id = newUUID("folder/" + title)
+	st.folders[title] = id
+	st.data.Folders = append(st.data.Folders, FolderItem{UUID: id, Title: title})
+	return id
+}
+
+// newAttachment -
+func newAttachment(name string, data []byte) Attachment {
+	var kind = strings.SplitN(http.DetectContentType(data), ";", 2)[0]
+	return Attachment{
+		Name: name,
+		Kind: kind,
+		Data: base64.StdEncoding.EncodeToString(data),
+	}
+}
+
+// newField -
+func newField(f field.FieldInterface) Field {
+	var out = Field{
+		Type:  "text",
+		Label: f.GetKey(),
+		Value: f.GetValueString(),
+	}
+
+	switch {
+	case f.IsType(field.SecretUsernameField), f.GetKey() == "username":
+		out.Type = "username"
+	case f.IsType(field.SecretPasswordField):
+		out.Type = "password"
+	case f.IsType(field.SecretURLField):
+		out.Type = "url"
+	case f.IsType(field.SecretOTPField), f.GetKey() == "totp":
+		out.Type = "totp"
+	case f.GetKey() == "email":
+		out.Type = "email"
+	case f.IsMultiline():
+		out.Type = "multiline"
+	}
+
+	if label, ok := fieldLabels[out.Label]; ok {
+		out.Label = label
+	}
+
+	if f.IsSensitive() || out.Type == "password" || out.Type == "totp" {
+		out.Sensitive = 1
+	}
+
+	return out
+}
+
+// Save -
+func (st *JsonStore) Save(fields []field.FieldInterface, p string) (bool, error) {
+	p = st.items.Unique(p)
+	var levels []string
+	for _, level := range strings.Split(filepath.ToSlash(p), "/") {
+		if level != "" {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		return false, fmt.Errorf("invalid secret path: '%s'", p)
+	}
+
+	var item = DataItem{
+		Title:       levels[len(levels)-1],
+		Fields:      []Field{},
+		Folders:     []string{},
+		Attachments: []Attachment{},
+	}
+	levels = levels[:len(levels)-1]
+	if len(levels) > 0 {
+		switch levels[0] {
+		case "trash":
+			item.Trashed = 1
+		case "archive":
+			item.Archived = 1
+		case "favorite":
+			item.Favorite = 1
+		}
+		if item.Trashed+item.Archived+item.Favorite > 0 {
+			levels = levels[1:]
+		}
+	}
+
+	var id string
+	var tags []string
+	var hasTitle, hasLogin bool
+	for _, f := range fields {
+		switch f.GetType() {
+		case field.SecretTitleField:
+			if !hasTitle {
+				item.Title, hasTitle = f.GetValueString(), true
+				continue
+			}
+		case field.SecretIDField:
+			if f.GetKey() == "enpass_uuid" {
+				item.UUID = f.GetValueString()
+			} else if id == "" {
+				id = f.GetValueString()
+			}
+			continue
+		case field.SecretTagsField:
+			tags = append(tags, utils.ParseTags(f.GetValueString())...)
+			continue
+		case field.SecretAttachmentField:
+			item.Attachments = append(item.Attachments, newAttachment(f.GetKey(), f.GetValue()))
+			continue
+		case field.SecretUsernameField:
+			if f.GetKey() == "subtitle" {
+				item.Subtitle = f.GetValueString()
+				continue
+			}
+			hasLogin = true
+		case field.SecretPasswordField:
+			hasLogin = true
+		}
+
+		switch {
+		case f.GetKey() == "category" && item.Category == "":
+			item.Category = f.GetValueString()
+			continue
+		case f.GetKey() == "note" && f.IsMultiline() && item.Note == "":
+			item.Note = f.GetValueString()
+			continue
+		case strings.HasPrefix(f.GetKey(), "attachment - ") && f.IsMultiline():
+			// text attachments are exported by enpass source as multiline fields
+			var name = strings.TrimPrefix(f.GetKey(), "attachment - ")
+			item.Attachments = append(item.Attachments, newAttachment(name, f.GetValue()))
+			continue
+		}
+
+		item.Fields = append(item.Fields, newField(f))
+	}
+
+	if len(levels) > 0 && (levels[0] == item.Category || item.Category == "" && utils.InList(categories, levels[0])) {
+		item.Category, levels = levels[0], levels[1:]
+	}
+
+	if item.Category == "" {
+		item.Category = "note"
+		if hasLogin {
+			item.Category = "login"
+		}
+	}
+
+	if item.Subtitle == "" {
+		for _, f := range item.Fields {
+			if f.Type == "username" || f.Type == "email" {
+				item.Subtitle = f.Value
+				break
+			}
+		}
+	}
+
+	// enpass source exports item folders as tags
+	if len(tags) == 0 && len(levels) > 0 {
+		tags = []string{strings.Join(levels, "/")}
+	}
+	for _, t := range tags {
+		item.Folders = append(item.Folders, st.folderUUID(t))
+	}
+
+	if item.UUID == "" {
+		item.UUID = newUUID(utils.FirstNonEmpty(id, p))
+	}
+
+	st.data.Items = append(st.data.Items, item)
+
+	var l = st.logger.WithField("enpasskey", p)
+	existing, ok := st.existing[item.UUID]
+	delete(st.existing, item.UUID)
+	if ok {
+		a, _ := json.Marshal(existing)
+		b, _ := json.Marshal(item)
+		if bytes.Equal(a, b) {
+			l.Debug("enpass item already in actual state")
+			return false, nil
+		}
+	}
+
+	l.Info("secret will be updated")
+	return true, nil
+}
+
+// Cleanup - items of existing file which were not saved during this run are not written back
+func (st *JsonStore) Cleanup() (bool, error) {
+	for _, item := range st.existing {
+		st.logger.WithField("type", "cleaner").
+			WithField("enpasskey", item.Title).
+			Info("enpass item will be deleted")
+	}
+	return len(st.existing) > 0, nil
+}
+
+// Close - writes json file
+func (st *JsonStore) Close() error {
+	if st.dryrun {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(st.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if current, err := os.ReadFile(st.path); err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+
+	var tmpPath = st.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write enpass json '%s': %s", st.path, err.Error())
+	}
+
+	err = os.Rename(tmpPath, st.path)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("cannot write enpass json '%s': %s", st.path, err.Error())
+	}
+
+	st.logger.WithField("path", st.path).Info("enpass json has been written")
+	return nil
+}
+
+// NewJsonStore - existing file is used to keep folder ids and to detect changes
+func NewJsonStore(dataPath string, dryrun bool, logger *logrus.Logger) (st *JsonStore, err error) {
+	absPath, err := filepath.Abs(dataPath)
+	if err != nil {
+		return
+	}
+
+	st = &JsonStore{
+		path:     absPath,
+		existing: make(map[string]DataItem),
+		folders:  make(map[string]string),
+		data:     Data{Folders: []FolderItem{}, Items: []DataItem{}},
+		items:    utils.NewUniqueStrings(logger),
+		dryrun:   dryrun,
+		logger:   logger,
+	}
+
+	b, err := os.ReadFile(absPath)
+	switch {
+	case os.IsNotExist(err):
+		return st, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var d Data
+	err = json.Unmarshal(b, &d)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse enpass json '%s': %s", absPath, err.Error())
+	}
+
+	for _, f := range d.Folders {
+		st.folders[f.Title] = f.UUID
+	}
+	for _, item := range d.Items {
+		st.existing[item.UUID] = item
+	}
+
+	return st, nil
+}
@@ -0,0 +1,161 @@
+package enpass
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/revengel/enpass2gopass/store/keepass"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// keepassItems - writes entry into new keepass database and reads it back by keepass source
+func keepassItems(t *testing.T) []store.StoreSourceItem {
+	t.Helper()
+	var dbPath = filepath.Join(t.TempDir(), "source.kdbx")
+	var creds = gokeepasslib.NewPasswordCredentials("secret")
+	var opts = keepass.NewDatabaseOptions()
+	opts.Kdf, opts.Iterations = keepass.KdfAES, 1
+
+	st, err := keepass.NewStore(dbPath, creds, opts, "login", false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = st.Save([]field.FieldInterface{
+		field.NewTitleField("", "GitHub"),
+		field.NewUsernameField("", "octocat"),
+		field.NewPasswordField("", "s3cr3t"),
+		field.NewUrlField("", "https://github.com"),
+		field.NewOTPField("", "otpauth://totp/GitHub:octocat?issuer=GitHub&secret=JBSWY3DPEHPK3PXP"),
+		field.NewSimpleField("pin", []byte("1234"), false, true),
+		field.NewSimpleField("recovery", []byte("code1\ncode2"), true, false),
+		field.NewAttachmentField("key.bin", []byte{0x00, 0x01, 0x02}),
+	}, "work/github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := keepass.NewKeepassSource(dbPath, creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := src.LoadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+// exportItems - saves items into enpass json, returns count of changed items
+func exportItems(t *testing.T, p string, items []store.StoreSourceItem) (changed int) {
+	t.Helper()
+	st, err := NewJsonStore(p, false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range items {
+		secretPath, err := item.GetSecretPath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields, err := item.GetFields()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ok, err := st.Save(fields, secretPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			changed++
+		}
+	}
+
+	if _, err = st.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestJsonStoreRoundTrip(t *testing.T) {
+	var items = keepassItems(t)
+	var jsonPath = filepath.Join(t.TempDir(), "export.json")
+	if n := exportItems(t, jsonPath, items); n != 1 {
+		t.Fatalf("expected one written item, got %d", n)
+	}
+
+	src, err := NewEnpassJsonSource(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := src.LoadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 1 {
+		t.Fatalf("expected one item, got %d", len(exported))
+	}
+
+	p, err := exported[0].GetSecretPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != filepath.Join("login", "work", "github") {
+		t.Errorf("unexpected secret path %s", p)
+	}
+
+	fields, err := exported[0].GetFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values = make(map[string]field.FieldInterface)
+	for _, f := range fields {
+		values[f.GetKey()] = f
+	}
+
+	for _, tt := range []struct {
+		key   string
+		value string
+		t     field.FieldType
+	}{
+		{key: "title", value: "GitHub", t: field.SecretTitleField},
+		{key: "username", value: "octocat", t: field.SecretSimpleField},
+		{key: "password", value: "s3cr3t", t: field.SecretPasswordField},
+		{key: "website", value: "https://github.com", t: field.SecretURLField},
+		{key: "otpauth", value: "otpauth://totp/GitHub:octocat?issuer=GitHub&secret=JBSWY3DPEHPK3PXP", t: field.SecretOTPField},
+		{key: "pin", value: "1234", t: field.SecretSimpleField},
+		// keepass keeps multiline fields in notes
+		{key: "notes", value: "recovery\n\ncode1\ncode2\n", t: field.SecretSimpleField},
+		{key: "key.bin", value: "\x00\x01\x02", t: field.SecretAttachmentField},
+	} {
+		f, ok := values[tt.key]
+		if !ok {
+			t.Errorf("field %s is lost, fields: %v", tt.key, values)
+			continue
+		}
+		if f.GetValueString() != tt.value || !f.IsType(tt.t) {
+			t.Errorf("field %s: expected %q of type %s, got %q of type %s", tt.key, tt.value, tt.t, f.GetValueString(), f.GetType())
+		}
+	}
+
+	if f, ok := values["notes"]; ok && !f.IsMultiline() {
+		t.Error("multiline field is exported as single line")
+	}
+
+	// repeated export of the same items changes nothing
+	if n := exportItems(t, jsonPath, items); n != 0 {
+		t.Errorf("repeated export has changed %d items", n)
+	}
+}
@@ -16,6 +16,7 @@ type DataItem struct {
 	Archived uint8 `json:"archived"`
 	Favorite uint8 `json:"favorite"`
 
+	UUID     string `json:"uuid"`
 	Category string `json:"category"`
 
 	Title    string `json:"title"`
@@ -42,6 +43,11 @@ func (i DataItem) IsFavorite() bool {
 	return i.Favorite == 1
 }
 
+// GetUUID -
+func (i DataItem) GetUUID() string {
+	return i.UUID
+}
+
 // GetCategory -
 func (i DataItem) GetCategory() string {
 	return i.Category
@@ -90,6 +96,24 @@ func (i DataItem) GetFoldersStr() string {
 	return fmt.Sprintf("[%s]", strings.Join(i.GetFolders(), ", "))
 }
 
+// getFieldValue - value of first not deleted field of given type
+func (i DataItem) getFieldValue(t string) string {
+	for _, f := range i.Fields {
+		if !f.IsDeleted() && f.CheckType(t) && f.GetValue() != "" {
+			return f.GetValue()
+		}
+	}
+	return ""
+}
+
+// GetOTPAuthURI - converts totp field value into otpauth uri, issuer is taken
+// from title or url and account from username, email or subtitle
+func (i DataItem) GetOTPAuthURI(f Field) (string, bool) {
+	var issuer = utils.OTPIssuer(i.GetTitle(), i.getFieldValue("url"))
+	var account = utils.FirstNonEmpty(i.getFieldValue("username"), i.getFieldValue("email"), i.GetSubtitle())
+	return utils.OTPAuthURI(f.GetValue(), issuer, account)
+}
+
 // GetFields -
 func (i DataItem) GetFields() (out []field.FieldInterface, err error) {
 	if v := i.GetTitle(); v != "" {
@@ -97,6 +121,11 @@ func (i DataItem) GetFields() (out []field.FieldInterface, err error) {
 		out = append(out, f)
 	}
 
+	if v := i.GetUUID(); v != "" {
+		f := field.NewIDField("enpass_uuid", v)
+		out = append(out, f)
+	}
+
 	if v := i.GetSubtitle(); v != "" {
 		f := field.NewUsernameField("subtitle", v)
 		out = append(out, f)
@@ -129,6 +158,10 @@ func (i DataItem) GetFields() (out []field.FieldInterface, err error) {
 
 		var labelName = f.GetLabel()
 		if f.CheckType("totp") {
+			if uri, ok := i.GetOTPAuthURI(f); ok {
+				out = append(out, field.NewOTPField("", uri))
+				continue
+			}
 			labelName = "totp"
 		}
 
@@ -0,0 +1,79 @@
+package enpass
+
+import (
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+const (
+	// JsonSourceName -
+	JsonSourceName = "enpassJsonSource"
+	// VaultSourceName -
+	VaultSourceName = "enpassVaultSource"
+	// JsonDestinationName -
+	JsonDestinationName = "enpassJsonDestination"
+)
+
+func init() {
+	store.RegisterSource(store.SourceProvider{
+		Provider: store.Provider{
+			Name:        JsonSourceName,
+			Description: "Enpass JSON export",
+			FlagPrefix:  "source-enpass-json",
+			Options: []store.Option{
+				store.StringOption("path", "", "source enpass json path").Require(),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreSource, error) {
+			s, err := NewEnpassJsonSource(opts.String("path"))
+			if err != nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	})
+
+	store.RegisterSource(store.SourceProvider{
+		Provider: store.Provider{
+			Name:        VaultSourceName,
+			Description: "Enpass 6 vault, decrypted with master password and optional key file; attachments kept outside of vault database are skipped",
+			FlagPrefix:  "source-enpass-vault",
+			Options: []store.Option{
+				store.StringOption("path", "", "source enpass vault directory or vault.enpassdb path").Require(),
+				store.StringOption("password-env", "ENPASS_PASSWORD", "environment variable with source enpass vault master password"),
+				store.StringOption("password-file", "", "file with source enpass vault master password"),
+				store.StringOption("key-file", "", "source enpass vault key file"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreSource, error) {
+			password, _, err := utils.ReadPassword(opts.String("password-env"), opts.String("password-file"), true, "Enpass master password: ")
+			if err != nil {
+				return nil, err
+			}
+
+			s, err := NewEnpassVaultSource(opts.String("path"), password, opts.String("key-file"), env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	})
+
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        JsonDestinationName,
+			Description: "Enpass JSON file which can be imported into Enpass",
+			FlagPrefix:  "destination-enpass-json",
+			Options: []store.Option{
+				store.StringOption("path", "", "destination enpass json export path").Require(),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			st, err := NewJsonStore(opts.String("path"), env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
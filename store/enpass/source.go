@@ -32,15 +32,12 @@ func (self EnpassSource) LoadData() (o []store.StoreSourceItem, err error) {
 		return
 	}
 
-	var foldersMap = d.GetFoldersMap()
-	var items []store.StoreSourceItem
-	for _, item := range d.Items {
-		var folders = foldersMap.GetFolders(item.Folders)
-		item.Folders = folders
-		items = append(items, item)
-	}
+	return d.GetSourceItems(), nil
+}
 
-	return items, nil
+// GetSourcePath -
+func (self EnpassSource) GetSourcePath() string {
+	return self.path
 }
 
 func NewEnpassJsonSource(dataPath string) (o *EnpassSource, err error) {
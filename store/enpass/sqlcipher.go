@@ -0,0 +1,113 @@
+package enpass
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const sqlcipherSaltSize = 16
+
+// ErrInvalidVaultKey - returned when page hmac does not match derived key
+var ErrInvalidVaultKey = errors.New("invalid master password or key file")
+
+// sqlcipherParams - page layout and hmac settings of sqlcipher version
+type sqlcipherParams struct {
+	name     string
+	pageSize int
+	hash     func() hash.Hash
+	hmacSize int
+}
+
+func (p sqlcipherParams) reserve() int {
+	var r = aes.BlockSize + p.hmacSize
+	if m := r % aes.BlockSize; m != 0 {
+		r += aes.BlockSize - m
+	}
+	return r
+}
+
+var sqlcipherVersions = []sqlcipherParams{
+	{name: "sqlcipher4", pageSize: 4096, hash: sha512.New, hmacSize: sha512.Size},
+	{name: "sqlcipher3", pageSize: 1024, hash: sha1.New, hmacSize: sha1.Size},
+}
+
+// decryptSqlcipher - decrypts database encrypted with raw 32 bytes key into memory;
+// sqlcipher 4 and 3 page layouts are detected by page hmac
+func decryptSqlcipher(data, key []byte) ([]byte, error) {
+	if len(data) < sqlcipherSaltSize {
+		return nil, errors.New("vault file is too small")
+	}
+
+	var salt = data[:sqlcipherSaltSize]
+	for _, params := range sqlcipherVersions {
+		if len(data) < params.pageSize || len(data)%params.pageSize != 0 {
+			continue
+		}
+
+		var hmacSalt = make([]byte, len(salt))
+		for i, b := range salt {
+			hmacSalt[i] = b ^ 0x3a
+		}
+		var hmacKey = pbkdf2.Key(key, hmacSalt, 2, len(key), params.hash)
+
+		if !params.verifyPage(data[:params.pageSize], 1, hmacKey) {
+			continue
+		}
+
+		out := make([]byte, len(data))
+		for n := 0; n*params.pageSize < len(data); n++ {
+			var page = data[n*params.pageSize : (n+1)*params.pageSize]
+			if !params.verifyPage(page, n+1, hmacKey) {
+				return nil, errors.New("vault page authentication failed")
+			}
+
+			err := params.decryptPage(out[n*params.pageSize:(n+1)*params.pageSize], page, n+1, key)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	return nil, ErrInvalidVaultKey
+}
+
+func (p sqlcipherParams) pageOffset(pageNo int) int {
+	if pageNo == 1 {
+		return sqlcipherSaltSize
+	}
+	return 0
+}
+
+func (p sqlcipherParams) verifyPage(page []byte, pageNo int, hmacKey []byte) bool {
+	var end = p.pageSize - p.reserve()
+	var mac = hmac.New(p.hash, hmacKey)
+	mac.Write(page[p.pageOffset(pageNo) : end+aes.BlockSize])
+	var pgno [4]byte
+	binary.LittleEndian.PutUint32(pgno[:], uint32(pageNo))
+	mac.Write(pgno[:])
+	return hmac.Equal(mac.Sum(nil), page[end+aes.BlockSize:end+aes.BlockSize+p.hmacSize])
+}
+
+func (p sqlcipherParams) decryptPage(dst, page []byte, pageNo int, key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	var start, end = p.pageOffset(pageNo), p.pageSize - p.reserve()
+	var iv = page[end : end+aes.BlockSize]
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(dst[start:end], page[start:end])
+	if pageNo == 1 {
+		copy(dst, sqliteHeader)
+	}
+	return nil
+}
@@ -0,0 +1,430 @@
+package enpass
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// minimal read-only sqlite reader, it works with in-memory database image
+// so decrypted vault never touches the disk: sqlite drivers open files
+// through VFS and would need decrypted copy or temporary file. Vault is
+// untrusted input, every offset is bounds checked and every page is
+// visited once per table, so damaged image returns error instead of
+// panicking or looping
+
+const sqliteHeader = "SQLite format 3\x00"
+
+var errSqliteCorrupted = errors.New("sqlite database is corrupted")
+
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int
+	tables   map[string]sqliteTable
+}
+
+type sqliteTable struct {
+	name     string
+	rootPage int
+	columns  []string
+	rowidCol int
+}
+
+// sqliteRow - table row, column name to value (int64, float64, string, []byte or nil)
+type sqliteRow map[string]interface{}
+
+func (r sqliteRow) String(k string) string {
+	switch v := r[k].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return fmt.Sprintf("%d", v)
+	case float64:
+		return fmt.Sprintf("%g", v)
+	}
+	return ""
+}
+
+func (r sqliteRow) Bytes(k string) []byte {
+	switch v := r[k].(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+func (r sqliteRow) Int(k string) int64 {
+	switch v := r[k].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func openSqlite(data []byte) (*sqliteDB, error) {
+	if len(data) < 100 || string(data[:16]) != sqliteHeader {
+		return nil, errors.New("not a sqlite database")
+	}
+
+	var pageSize = int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+
+	// page size is power of two between 512 and 65536, usable size is at least 480
+	var usable = pageSize - int(data[20])
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 || usable < 480 {
+		return nil, errSqliteCorrupted
+	}
+
+	db := &sqliteDB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   usable,
+		tables:   make(map[string]sqliteTable),
+	}
+
+	var master = sqliteTable{
+		name:     "sqlite_master",
+		rootPage: 1,
+		columns:  []string{"type", "name", "tbl_name", "rootpage", "sql"},
+		rowidCol: -1,
+	}
+
+	rows, err := db.readTable(master)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if row.String("type") != "table" {
+			continue
+		}
+
+		var t = sqliteTable{
+			name:     row.String("name"),
+			rootPage: int(row.Int("rootpage")),
+			rowidCol: -1,
+		}
+		t.columns, t.rowidCol = parseCreateTable(row.String("sql"))
+		db.tables[t.name] = t
+	}
+
+	return db, nil
+}
+
+// parseCreateTable - extracts column names and INTEGER PRIMARY KEY column index from table sql
+func parseCreateTable(sql string) (columns []string, rowidCol int) {
+	rowidCol = -1
+	var start, end = strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if start < 0 || end <= start {
+		return
+	}
+
+	var defs []string
+	var depth, last int
+	var body = sql[start+1 : end]
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				defs = append(defs, body[last:i])
+				last = i + 1
+			}
+		}
+	}
+	defs = append(defs, body[last:])
+
+	var constraints = []string{"PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT"}
+	for _, def := range defs {
+		var parts = strings.Fields(def)
+		if len(parts) == 0 {
+			continue
+		}
+
+		var upper = strings.ToUpper(strings.Join(parts, " "))
+		var first = strings.ToUpper(parts[0])
+		var isConstraint bool
+		for _, c := range constraints {
+			if first == c {
+				isConstraint = true
+			}
+		}
+		if isConstraint {
+			continue
+		}
+
+		if strings.HasPrefix(upper, strings.ToUpper(parts[0])+" INTEGER PRIMARY KEY") {
+			rowidCol = len(columns)
+		}
+		columns = append(columns, strings.Trim(parts[0], "\"'`[]"))
+	}
+
+	return
+}
+
+func (db *sqliteDB) page(n int) ([]byte, error) {
+	var off = (n - 1) * db.pageSize
+	if n < 1 || off+db.pageSize > len(db.data) {
+		return nil, errSqliteCorrupted
+	}
+	return db.data[off : off+db.pageSize], nil
+}
+
+// Rows - reads all rows of the table
+func (db *sqliteDB) Rows(table string) ([]sqliteRow, error) {
+	t, ok := db.tables[table]
+	if !ok {
+		return nil, fmt.Errorf("table '%s' does not exist", table)
+	}
+	return db.readTable(t)
+}
+
+// HasColumn - checks column existence
+func (db *sqliteDB) HasColumn(table, column string) bool {
+	t, ok := db.tables[table]
+	if !ok {
+		return false
+	}
+	for _, c := range t.columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *sqliteDB) readTable(t sqliteTable) (rows []sqliteRow, err error) {
+	var seen = make(map[int]bool)
+	err = db.walkTree(t.rootPage, seen, func(rowid int64, payload []byte) error {
+		values, err := decodeRecord(payload)
+		if err != nil {
+			return err
+		}
+
+		var row = make(sqliteRow)
+		for i, c := range t.columns {
+			if i < len(values) {
+				row[c] = values[i]
+			}
+			if i == t.rowidCol && row[c] == nil {
+				row[c] = rowid
+			}
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	return
+}
+
+// visit - returns page which has not been visited yet,
+// page of valid database belongs to single tree or overflow chain
+func (db *sqliteDB) visit(n int, seen map[int]bool) ([]byte, error) {
+	if seen[n] {
+		return nil, errSqliteCorrupted
+	}
+	seen[n] = true
+	return db.page(n)
+}
+
+func (db *sqliteDB) walkTree(pageNo int, seen map[int]bool, fn func(rowid int64, payload []byte) error) error {
+	p, err := db.visit(pageNo, seen)
+	if err != nil {
+		return err
+	}
+
+	var hdr = 0
+	if pageNo == 1 {
+		hdr = 100
+	}
+	if len(p) < hdr+12 {
+		return errSqliteCorrupted
+	}
+
+	var pageType = p[hdr]
+	var cells = int(binary.BigEndian.Uint16(p[hdr+3 : hdr+5]))
+	switch pageType {
+	case 0x05:
+		var ptrs = hdr + 12
+		if ptrs+2*cells > len(p) {
+			return errSqliteCorrupted
+		}
+		for i := 0; i < cells; i++ {
+			var off = int(binary.BigEndian.Uint16(p[ptrs+2*i:]))
+			if off+4 > len(p) {
+				return errSqliteCorrupted
+			}
+			err = db.walkTree(int(binary.BigEndian.Uint32(p[off:])), seen, fn)
+			if err != nil {
+				return err
+			}
+		}
+		return db.walkTree(int(binary.BigEndian.Uint32(p[hdr+8:])), seen, fn)
+	case 0x0d:
+		var ptrs = hdr + 8
+		if ptrs+2*cells > len(p) {
+			return errSqliteCorrupted
+		}
+		for i := 0; i < cells; i++ {
+			var off = int(binary.BigEndian.Uint16(p[ptrs+2*i:]))
+			rowid, payload, err := db.leafCell(p, off, seen)
+			if err != nil {
+				return err
+			}
+			err = fn(rowid, payload)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unexpected sqlite page type 0x%02x", pageType)
+}
+
+func (db *sqliteDB) leafCell(p []byte, off int, seen map[int]bool) (rowid int64, payload []byte, err error) {
+	if off >= len(p) {
+		return 0, nil, errSqliteCorrupted
+	}
+
+	size, n := readVarint(p[off:])
+	off += n
+	rowidU, n := readVarint(p[off:])
+	off += n
+	rowid = int64(rowidU)
+
+	if size > uint64(len(db.data)) {
+		return 0, nil, errSqliteCorrupted
+	}
+
+	var total = int(size)
+	var maxLocal = db.usable - 35
+	if total <= maxLocal {
+		if off+total > len(p) {
+			return 0, nil, errSqliteCorrupted
+		}
+		return rowid, p[off : off+total], nil
+	}
+
+	var minLocal = (db.usable-12)*32/255 - 23
+	var local = minLocal + (total-minLocal)%(db.usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if off+local+4 > len(p) {
+		return 0, nil, errSqliteCorrupted
+	}
+
+	payload = make([]byte, 0, total)
+	payload = append(payload, p[off:off+local]...)
+	var next = int(binary.BigEndian.Uint32(p[off+local:]))
+	for len(payload) < total {
+		ovf, err := db.visit(next, seen)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		var chunk = total - len(payload)
+		if chunk > db.usable-4 {
+			chunk = db.usable - 4
+		}
+		payload = append(payload, ovf[4:4+chunk]...)
+		next = int(binary.BigEndian.Uint32(ovf))
+	}
+
+	return rowid, payload, nil
+}
+
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
+
+func decodeRecord(payload []byte) (values []interface{}, err error) {
+	hdrSize, n := readVarint(payload)
+	if hdrSize > uint64(len(payload)) {
+		return nil, errSqliteCorrupted
+	}
+
+	var types []uint64
+	for pos := n; pos < int(hdrSize); {
+		t, n := readVarint(payload[pos:])
+		types = append(types, t)
+		pos += n
+	}
+
+	var body = payload[hdrSize:]
+	for _, t := range types {
+		var size int
+		switch {
+		case t == 0, t == 8, t == 9:
+			size = 0
+		case t >= 1 && t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6, t == 7:
+			size = 8
+		case t >= 12:
+			if (t-12)/2 > uint64(len(body)) {
+				return nil, errSqliteCorrupted
+			}
+			size = int(t-12) / 2
+		default:
+			return nil, errSqliteCorrupted
+		}
+
+		if size > len(body) {
+			return nil, errSqliteCorrupted
+		}
+
+		var raw = body[:size]
+		body = body[size:]
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t <= 6:
+			var v int64
+			for _, b := range raw {
+				v = v<<8 | int64(b)
+			}
+			// sign extension
+			var shift = uint(64 - 8*size)
+			values = append(values, v<<shift>>shift)
+		case t == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(raw)))
+		case t%2 == 0:
+			values = append(values, append([]byte(nil), raw...))
+		default:
+			values = append(values, string(raw))
+		}
+	}
+
+	return values, nil
+}
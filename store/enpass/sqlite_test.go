@@ -0,0 +1,125 @@
+package enpass
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+// testSqliteImage - decrypted image of fixture vault
+func testSqliteImage(t testing.TB) []byte {
+	t.Helper()
+	raw, err := os.ReadFile("testdata/vault/vault.enpassdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s = EnpassVaultSource{password: testVaultPassword}
+	key, err := s.deriveKey(raw[:sqlcipherSaltSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := decryptSqlcipher(raw, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
+
+func readAllTables(data []byte) error {
+	db, err := openSqlite(data)
+	if err != nil {
+		return err
+	}
+
+	for name := range db.tables {
+		if _, err = db.Rows(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSqliteRows(t *testing.T) {
+	db, err := openSqlite(testSqliteImage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for table, count := range map[string]int{"item": 3, "itemfield": 7, "folder": 1, "folder_items": 1, "attachment": 2} {
+		rows, err := db.Rows(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != count {
+			t.Errorf("table %s: expected %d rows, got %d", table, count, len(rows))
+		}
+	}
+
+	if !db.HasColumn("attachment", "data") || db.HasColumn("attachment", "hash") {
+		t.Error("columns of attachment table are parsed wrong")
+	}
+
+	if _, err = db.Rows("missing"); err == nil {
+		t.Error("missing table is read")
+	}
+}
+
+func TestSqliteCorrupted(t *testing.T) {
+	var image = testSqliteImage(t)
+
+	// cell pointers array of root page exceeds the page
+	var broken = append([]byte(nil), image...)
+	binary.BigEndian.PutUint16(broken[100+3:], 0xffff)
+	if err := readAllTables(broken); err == nil {
+		t.Error("page with too many cells is read")
+	}
+
+	// every byte of the image is damaged in turn,
+	// reader has to return error or rows but never panic
+	for i := range image {
+		var damaged = append([]byte(nil), image...)
+		damaged[i] ^= 0xff
+		_ = readAllTables(damaged)
+	}
+
+	for _, size := range []int{0, 99, 100, 512, 4096} {
+		if err := readAllTables(image[:size]); err == nil {
+			t.Errorf("truncated image of %d bytes is read", size)
+		}
+	}
+}
+
+func TestSqlitePageCycle(t *testing.T) {
+	var image = testSqliteImage(t)
+
+	// schema page turned into interior page whose children and right pointer
+	// are the page itself
+	var cyclic = append([]byte(nil), image...)
+	const hdr, cell = 100, 1000
+	cyclic[hdr] = 0x05
+	binary.BigEndian.PutUint16(cyclic[hdr+3:], 500)
+	binary.BigEndian.PutUint32(cyclic[hdr+8:], 1)
+	for i := 0; i < 500; i++ {
+		binary.BigEndian.PutUint16(cyclic[hdr+12+2*i:], cell)
+	}
+	binary.BigEndian.PutUint32(cyclic[cell:], 1)
+
+	if err := readAllTables(cyclic); err != errSqliteCorrupted {
+		t.Errorf("expected corrupted database error, got %v", err)
+	}
+}
+
+// FuzzOpenSqlite - seeds run with regular tests; minimization of vault image is slow,
+// fuzz with `go test -fuzz FuzzOpenSqlite -fuzzminimizetime 0 ./store/enpass`
+func FuzzOpenSqlite(f *testing.F) {
+	var image = testSqliteImage(f)
+	f.Add(image)
+	f.Add(image[:4096])
+	f.Add(image[:100])
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_ = readAllTables(data)
+	})
+}
@@ -0,0 +1,202 @@
+//go:build ignore
+
+// genvault - generates encrypted Enpass 6 vault fixtures used by vault tests,
+// requires sqlite3 command line tool; run with `go generate ./store/enpass`
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	password = "enpass2gopass-test"
+	pageSize = 4096
+	reserve  = 80
+
+	loginUUID   = "a2ec30c0-aeed-41f7-aed7-cc50e69ff506"
+	deletedUUID = "b2ec30c0-aeed-41f7-aed7-cc50e69ff507"
+	cardUUID    = "d2ec30c0-aeed-41f7-aed7-cc50e69ff509"
+	attachUUID  = "c2ec30c0-aeed-41f7-aed7-cc50e69ff508"
+	folderUUID  = "e2ec30c0-aeed-41f7-aed7-cc50e69ff50a"
+)
+
+// keyFileData - hex secret of key file
+var keyFileData = strings.Repeat("0123456789abcdef", 4)
+
+const schema = `
+PRAGMA page_size=4096;
+.filectrl reserve_bytes 80
+CREATE TABLE item (uuid TEXT PRIMARY KEY, created_at INTEGER, title TEXT, subtitle TEXT, note TEXT, icon TEXT,
+	favorite INTEGER, trashed INTEGER, archived INTEGER, deleted INTEGER, category TEXT, key BLOB);
+CREATE TABLE itemfield (item_uuid TEXT, item_field_uid INTEGER, label TEXT, value TEXT, deleted INTEGER,
+	sensitive INTEGER, type TEXT, orde INTEGER);
+CREATE TABLE folder (uuid TEXT PRIMARY KEY, title TEXT, icon TEXT, parent_uuid TEXT);
+CREATE TABLE folder_items (folder_uuid TEXT, item_uuid TEXT);
+`
+
+// itemKey - deterministic 32 bytes AES key followed by 12 bytes GCM nonce
+func itemKey(uuid string) []byte {
+	var sum = sha512.Sum512([]byte(uuid))
+	return sum[:44]
+}
+
+func seal(uuid, value string) string {
+	var key = itemKey(uuid)
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	aad, err := hex.DecodeString(strings.ReplaceAll(uuid, "-", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return hex.EncodeToString(gcm.Seal(nil, key[32:], []byte(value), aad))
+}
+
+func items(external bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO item VALUES('%s',0,'GitHub','octocat','login note','',1,0,0,0,'login',x'%x');\n",
+		loginUUID, itemKey(loginUUID))
+	fmt.Fprintf(&b, "INSERT INTO item VALUES('%s',0,'Gone','','','',0,0,0,1,'login',x'%x');\n",
+		deletedUUID, itemKey(deletedUUID))
+	fmt.Fprintf(&b, "INSERT INTO item VALUES('%s',0,'Bank card','','','',0,0,0,0,'creditcard',x'%x');\n",
+		cardUUID, itemKey(cardUUID))
+
+	fmt.Fprintf(&b, "INSERT INTO itemfield VALUES('%s',1,'Username','octocat',0,0,'username',1);\n", loginUUID)
+	fmt.Fprintf(&b, "INSERT INTO itemfield VALUES('%s',2,'Password','%s',0,1,'password',2);\n", loginUUID, seal(loginUUID, "s3cr3t"))
+	fmt.Fprintf(&b, "INSERT INTO itemfield VALUES('%s',3,'Website','https://github.com',0,0,'url',3);\n", loginUUID)
+	// long value is stored in overflow pages
+	fmt.Fprintf(&b, "INSERT INTO itemfield VALUES('%s',4,'Recovery codes','%s',0,0,'multiline',4);\n",
+		loginUUID, strings.Repeat("code\n", 2000))
+	fmt.Fprintf(&b, "INSERT INTO itemfield VALUES('%s',5,'Old password','old',1,0,'password',5);\n", loginUUID)
+	fmt.Fprintf(&b, "INSERT INTO itemfield VALUES('%s',1,'Number','%s',0,1,'ccNumber',1);\n", cardUUID, seal(cardUUID, "4111111111111111"))
+	fmt.Fprintf(&b, "INSERT INTO itemfield VALUES('%s',1,'Password','gone',0,0,'password',1);\n", deletedUUID)
+
+	fmt.Fprintf(&b, "INSERT INTO folder VALUES('%s','Работа','',NULL);\n", folderUUID)
+	fmt.Fprintf(&b, "INSERT INTO folder_items VALUES('%s','%s');\n", folderUUID, loginUUID)
+
+	if external {
+		b.WriteString("CREATE TABLE attachment (uuid TEXT PRIMARY KEY, item_uuid TEXT, name TEXT, mime TEXT, key BLOB, hash TEXT, size INTEGER);\n")
+		fmt.Fprintf(&b, "INSERT INTO attachment VALUES('%s','%s','key.bin','application/octet-stream',x'%x','',6);\n",
+			attachUUID, loginUUID, itemKey(attachUUID))
+		return b.String()
+	}
+
+	var data, _ = hex.DecodeString(seal(attachUUID, "\x00\x01\x02bin"))
+	b.WriteString("CREATE TABLE attachment (uuid TEXT PRIMARY KEY, item_uuid TEXT, name TEXT, mime TEXT, key BLOB, data BLOB);\n")
+	fmt.Fprintf(&b, "INSERT INTO attachment VALUES('%s','%s','key.bin','application/octet-stream',x'%x',x'%x');\n",
+		attachUUID, loginUUID, itemKey(attachUUID), data)
+	fmt.Fprintf(&b, "INSERT INTO attachment VALUES('%s','%s','gone.bin','application/octet-stream',x'%x',x'%x');\n",
+		strings.Replace(attachUUID, "c2", "f2", 1), deletedUUID, itemKey(attachUUID), data)
+	return b.String()
+}
+
+// sqliteImage - builds plain sqlite database with page layout of sqlcipher 4
+func sqliteImage(sql string) []byte {
+	dir, err := os.MkdirTemp("", "genvault")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var p = filepath.Join(dir, "vault.db")
+	cmd := exec.Command("sqlite3", p)
+	cmd.Stdin = strings.NewReader(sql)
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return data
+}
+
+// encrypt - sqlcipher 4 encryption with raw key derived like Enpass does,
+// salt and page ivs are deterministic to keep fixtures stable
+func encrypt(data, secret []byte, kdfIter int) []byte {
+	var saltSum = sha256.Sum256([]byte("enpass2gopass"))
+	var salt = saltSum[:16]
+	var key = pbkdf2.Key(secret, salt, kdfIter, 64, sha512.New)[:32]
+
+	var hmacSalt = make([]byte, len(salt))
+	for i, b := range salt {
+		hmacSalt[i] = b ^ 0x3a
+	}
+	var hmacKey = pbkdf2.Key(key, hmacSalt, 2, len(key), sha512.New)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var out = make([]byte, len(data))
+	for n := 0; n*pageSize < len(data); n++ {
+		var page, dst = data[n*pageSize : (n+1)*pageSize], out[n*pageSize : (n+1)*pageSize]
+		var start, end = 0, pageSize - reserve
+		if n == 0 {
+			start = 16
+			copy(dst, salt)
+		}
+
+		var ivSum = sha256.Sum256(append(append([]byte(nil), salt...), byte(n), byte(n>>8)))
+		copy(dst[end:], ivSum[:16])
+		cipher.NewCBCEncrypter(block, dst[end:end+16]).CryptBlocks(dst[start:end], page[start:end])
+
+		var mac = hmac.New(sha512.New, hmacKey)
+		mac.Write(dst[start : end+16])
+		var pgno [4]byte
+		binary.LittleEndian.PutUint32(pgno[:], uint32(n+1))
+		mac.Write(pgno[:])
+		copy(dst[end+16:], mac.Sum(nil))
+	}
+	return out
+}
+
+func write(p string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	var plain = sqliteImage(schema + items(false))
+	write("testdata/vault/vault.enpassdb", encrypt(plain, []byte(password), 100000))
+	// vaults created by older Enpass versions do not have kdf_iter
+	write("testdata/vault/vault.json", []byte(`{"have_keyfile":0,"vault_icon":"vault","vault_name":"Primary"}`+"\n"))
+	write("testdata/kdfiter/vault.enpassdb", encrypt(plain, []byte(password), 320000))
+	write("testdata/kdfiter/vault.json", []byte(`{"have_keyfile":0,"kdf_algo":"pbkdf2","kdf_iter":320000,"vault_icon":"vault","vault_name":"Primary"}`+"\n"))
+
+	var keyFile = fmt.Sprintf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Key>\n    <Data>%s</Data>\n</Key>\n", keyFileData)
+	secret, _ := hex.DecodeString(keyFileData)
+	write("testdata/keyfile/vault.enpasskey", []byte(keyFile))
+	write("testdata/keyfile/vault.enpassdb", encrypt(plain, append([]byte(password), secret...), 100000))
+
+	var external = sqliteImage(schema + items(true))
+	write("testdata/external/vault.enpassdb", encrypt(external, []byte(password), 100000))
+}
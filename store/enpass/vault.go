@@ -0,0 +1,360 @@
+package enpass
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	vaultFileName     = "vault.enpassdb"
+	vaultInfoFileName = "vault.json"
+	// vaultKdfIter - pbkdf2 iterations of vaults which do not set kdf_iter
+	vaultKdfIter      = 100000
+	vaultMasterKeyLen = 64
+	vaultDbKeyLen     = 32
+	itemKeyLen        = 32
+)
+
+// EnpassVaultSource - reads encrypted Enpass 6 vault, decrypted data is kept in memory only
+type EnpassVaultSource struct {
+	path     string
+	password string
+	keyFile  string
+	logger   *logrus.Logger
+}
+
+// vaultInfo - vault metadata kept next to vault database
+type vaultInfo struct {
+	KdfIter *int `json:"kdf_iter"`
+}
+
+// vaultKeyFile - enpass key file
+type vaultKeyFile struct {
+	Data string `xml:"Data"`
+}
+
+// readKeyFile - returns key file secret which is appended to master password
+func readKeyFile(p string) ([]byte, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var kf vaultKeyFile
+	if err = xml.Unmarshal(b, &kf); err != nil || kf.Data == "" {
+		return b, nil
+	}
+
+	var data = strings.TrimSpace(kf.Data)
+	if decoded, err := hex.DecodeString(data); err == nil {
+		return decoded, nil
+	}
+	return []byte(data), nil
+}
+
+// kdfIter - returns pbkdf2 iterations from vault.json, newer Enpass versions
+// raised them, vaults without vault.json or kdf_iter use the old default
+func (s EnpassVaultSource) kdfIter() (int, error) {
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(s.path), vaultInfoFileName))
+	if os.IsNotExist(err) {
+		return vaultKdfIter, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var info vaultInfo
+	if err = json.Unmarshal(b, &info); err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %s", vaultInfoFileName, err.Error())
+	}
+
+	switch {
+	case info.KdfIter == nil:
+		return vaultKdfIter, nil
+	case *info.KdfIter <= 0:
+		return 0, fmt.Errorf("invalid kdf_iter in %s: %d", vaultInfoFileName, *info.KdfIter)
+	}
+	return *info.KdfIter, nil
+}
+
+// deriveKey - derives sqlcipher raw key from master password and key file
+func (s EnpassVaultSource) deriveKey(salt []byte) ([]byte, error) {
+	kdfIter, err := s.kdfIter()
+	if err != nil {
+		return nil, err
+	}
+
+	var secret = []byte(s.password)
+	if s.keyFile != "" {
+		kf, err := readKeyFile(s.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read key file: %s", err.Error())
+		}
+		secret = append(secret, kf...)
+	}
+
+	var masterKey = pbkdf2.Key(secret, salt, kdfIter, vaultMasterKeyLen, sha512.New)
+	return masterKey[:vaultDbKeyLen], nil
+}
+
+// decryptValue - decrypts sensitive value with item key (AES-256-GCM, item uuid as AAD)
+func decryptValue(itemKey []byte, uuid, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	if len(itemKey) < itemKeyLen+12 {
+		return "", errors.New("invalid item key")
+	}
+
+	ciphertext, err := hex.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+
+	aad, err := hex.DecodeString(strings.ReplaceAll(uuid, "-", ""))
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(itemKey[:itemKeyLen])
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := gcm.Open(nil, itemKey[itemKeyLen:itemKeyLen+12], ciphertext, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func (s EnpassVaultSource) openDb() (*sqliteDB, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) < sqlcipherSaltSize {
+		return nil, errors.New("vault file is too small")
+	}
+
+	key, err := s.deriveKey(raw[:sqlcipherSaltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := decryptSqlcipher(raw, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return openSqlite(plain)
+}
+
+// LoadData -
+func (s EnpassVaultSource) LoadData() (o []store.StoreSourceItem, err error) {
+	db, err := s.openDb()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open enpass vault '%s': %s", s.path, err.Error())
+	}
+
+	var d Data
+	folderRows, err := db.Rows("folder")
+	if err != nil {
+		return
+	}
+
+	for _, r := range folderRows {
+		d.Folders = append(d.Folders, FolderItem{
+			UUID:  r.String("uuid"),
+			Title: r.String("title"),
+		})
+	}
+
+	var itemFolders = make(map[string][]string)
+	folderItems, err := db.Rows("folder_items")
+	if err != nil {
+		return
+	}
+
+	for _, r := range folderItems {
+		var id = r.String("item_uuid")
+		itemFolders[id] = append(itemFolders[id], r.String("folder_uuid"))
+	}
+
+	itemRows, err := db.Rows("item")
+	if err != nil {
+		return
+	}
+
+	var itemKeys = make(map[string][]byte)
+	var items = make(map[string]*DataItem)
+	var order []string
+	for _, r := range itemRows {
+		if r.Int("deleted") == 1 {
+			continue
+		}
+
+		var id = r.String("uuid")
+		items[id] = &DataItem{
+			UUID:     id,
+			Trashed:  uint8(r.Int("trashed")),
+			Archived: uint8(r.Int("archived")),
+			Favorite: uint8(r.Int("favorite")),
+			Category: r.String("category"),
+			Title:    r.String("title"),
+			Subtitle: r.String("subtitle"),
+			Note:     r.String("note"),
+			Folders:  itemFolders[id],
+		}
+		itemKeys[id] = r.Bytes("key")
+		order = append(order, id)
+	}
+
+	fieldRows, err := db.Rows("itemfield")
+	if err != nil {
+		return
+	}
+
+	sort.SliceStable(fieldRows, func(i, j int) bool {
+		return fieldRows[i].Int("orde") < fieldRows[j].Int("orde")
+	})
+
+	for _, r := range fieldRows {
+		var id = r.String("item_uuid")
+		item, ok := items[id]
+		if !ok {
+			continue
+		}
+
+		var f = Field{
+			Deleted:   uint8(r.Int("deleted")),
+			Type:      r.String("type"),
+			Sensitive: uint8(r.Int("sensitive")),
+			Label:     r.String("label"),
+			Value:     r.String("value"),
+		}
+
+		if f.IsSensitive() && !f.IsDeleted() {
+			f.Value, err = decryptValue(itemKeys[id], id, f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decrypt field '%s' of item '%s': %s", f.Label, item.Title, err.Error())
+			}
+		}
+
+		item.Fields = append(item.Fields, f)
+	}
+
+	err = s.loadAttachments(db, items)
+	if err != nil {
+		return
+	}
+
+	for _, id := range order {
+		d.Items = append(d.Items, *items[id])
+	}
+
+	return d.GetSourceItems(), nil
+}
+
+// loadAttachments - reads attachments stored inside vault database; attachments
+// kept in separate files of vault directory are not supported, they are skipped
+// with warning, json export of such vault has to be used to import them
+func (s EnpassVaultSource) loadAttachments(db *sqliteDB, items map[string]*DataItem) error {
+	rows, err := db.Rows("attachment")
+	if err != nil {
+		// vault without attachments table
+		return nil
+	}
+
+	var hasItem = db.HasColumn("attachment", "item_uuid")
+	var external = !hasItem || !db.HasColumn("attachment", "data")
+
+	for _, r := range rows {
+		item, ok := items[r.String("item_uuid")]
+		if hasItem && !ok {
+			// attachment of deleted item
+			continue
+		}
+
+		if external || r["data"] == nil {
+			s.logger.WithFields(logrus.Fields{
+				"item":       r.String("item_uuid"),
+				"attachment": r.String("name"),
+			}).Warnf("attachment is stored outside of vault database and is skipped, export vault to json and use %s to import it", JsonSourceName)
+			continue
+		}
+
+		var data = r.Bytes("data")
+		if key := r.Bytes("key"); len(key) > 0 {
+			plain, err := decryptValue(key, r.String("uuid"), hex.EncodeToString(data))
+			if err != nil {
+				return fmt.Errorf("cannot decrypt attachment '%s': %s", r.String("name"), err.Error())
+			}
+			data = []byte(plain)
+		}
+
+		var kind = r.String("kind")
+		if kind == "" {
+			kind = r.String("mime")
+		}
+
+		item.Attachments = append(item.Attachments, Attachment{
+			Name: r.String("name"),
+			Kind: kind,
+			Data: base64.StdEncoding.EncodeToString(data),
+		})
+	}
+
+	return nil
+}
+
+// GetSourcePath -
+func (s EnpassVaultSource) GetSourcePath() string {
+	return s.path
+}
+
+// NewEnpassVaultSource - vaultPath is vault directory or vault.enpassdb file
+func NewEnpassVaultSource(vaultPath, password, keyFile string, logger *logrus.Logger) (o *EnpassVaultSource, err error) {
+	absPath, err := filepath.Abs(vaultPath)
+	if err != nil {
+		return
+	}
+
+	fi, err := os.Stat(absPath)
+	if err != nil {
+		return
+	}
+
+	if fi.IsDir() {
+		absPath = filepath.Join(absPath, vaultFileName)
+	}
+
+	return &EnpassVaultSource{
+		path:     absPath,
+		password: password,
+		keyFile:  keyFile,
+		logger:   logger,
+	}, nil
+}
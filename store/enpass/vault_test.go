@@ -0,0 +1,231 @@
+package enpass
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+)
+
+//go:generate go run testdata/genvault.go
+
+const testVaultPassword = "enpass2gopass-test"
+
+func testLogger() *logrus.Logger {
+	var l = logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+func loadVault(t *testing.T, vaultPath, password, keyFile string) ([]DataItem, error) {
+	t.Helper()
+	s, err := NewEnpassVaultSource(vaultPath, password, keyFile, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := s.LoadData()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []DataItem
+	for _, item := range items {
+		out = append(out, item.(DataItem))
+	}
+	return out, nil
+}
+
+func fieldsMap(t *testing.T, item DataItem) map[string]field.FieldInterface {
+	t.Helper()
+	fields, err := item.GetFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out = make(map[string]field.FieldInterface)
+	for _, f := range fields {
+		out[f.GetKey()] = f
+	}
+	return out
+}
+
+func TestVaultSource(t *testing.T) {
+	items, err := loadVault(t, "testdata/vault", testVaultPassword, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items without deleted one, got %d", len(items))
+	}
+
+	var login = items[0]
+	p, err := login.GetSecretPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != filepath.Join("favorite", "login", "rabota", "github") {
+		t.Errorf("unexpected secret path %s", p)
+	}
+
+	var fields = fieldsMap(t, login)
+	for k, v := range map[string]string{
+		"title":    "GitHub",
+		"subtitle": "octocat",
+		"username": "octocat",
+		"password": "s3cr3t",
+		"website":  "https://github.com",
+		"note":     "login note",
+	} {
+		if f, ok := fields[k]; !ok || f.GetValueString() != v {
+			t.Errorf("field %s: expected %q, got %v", k, v, f)
+		}
+	}
+
+	if !fields["password"].IsType(field.SecretPasswordField) {
+		t.Error("password field has wrong type")
+	}
+
+	// value is stored in sqlite overflow pages
+	if v := fields["recovery_codes"].GetValueString(); v != strings.Repeat("code\n", 2000) {
+		t.Errorf("overflowed value is corrupted, length %d", len(v))
+	}
+
+	if f, ok := fields["key.bin"]; !ok || !f.IsType(field.SecretAttachmentField) || f.GetValueString() != "\x00\x01\x02bin" {
+		t.Errorf("attachment is not decrypted: %v", f)
+	}
+
+	var card = fieldsMap(t, items[1])
+	if v := card["number"].GetValueString(); v != "4111111111111111" {
+		t.Errorf("sensitive card number is not decrypted: %q", v)
+	}
+}
+
+func TestVaultSourceKeyFile(t *testing.T) {
+	items, err := loadVault(t, "testdata/keyfile/vault.enpassdb", testVaultPassword, "testdata/keyfile/vault.enpasskey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].GetTitle() != "GitHub" {
+		t.Errorf("unexpected items %v", items)
+	}
+
+	_, err = loadVault(t, "testdata/keyfile/vault.enpassdb", testVaultPassword, "")
+	if err == nil || !strings.Contains(err.Error(), ErrInvalidVaultKey.Error()) {
+		t.Errorf("vault is opened without key file: %v", err)
+	}
+}
+
+func TestVaultSourceInvalidPassword(t *testing.T) {
+	_, err := loadVault(t, "testdata/vault", "wrong", "")
+	if err == nil || !strings.Contains(err.Error(), ErrInvalidVaultKey.Error()) {
+		t.Errorf("expected invalid key error, got %v", err)
+	}
+}
+
+func TestVaultSourceExternalAttachments(t *testing.T) {
+	var out bytes.Buffer
+	var logger = logrus.New()
+	logger.SetOutput(&out)
+
+	s, err := NewEnpassVaultSource("testdata/external", testVaultPassword, "", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.LoadData()
+	if err != nil {
+		t.Fatalf("vault with external attachments is not imported: %s", err.Error())
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+
+	if _, ok := fieldsMap(t, items[0].(DataItem))["key.bin"]; ok {
+		t.Error("external attachment is imported")
+	}
+	if !strings.Contains(out.String(), "level=warning") || !strings.Contains(out.String(), "key.bin") {
+		t.Errorf("skipped attachment is not reported: %q", out.String())
+	}
+}
+
+func TestVaultSourceKdfIter(t *testing.T) {
+	var tests = []struct {
+		name    string
+		path    string
+		kdfIter int
+	}{
+		{name: "without vault.json", path: "testdata/external", kdfIter: 100000},
+		{name: "without kdf_iter", path: "testdata/vault", kdfIter: 100000},
+		{name: "with kdf_iter", path: "testdata/kdfiter", kdfIter: 320000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewEnpassVaultSource(tt.path, testVaultPassword, "", testLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			kdfIter, err := s.kdfIter()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kdfIter != tt.kdfIter {
+				t.Errorf("kdf iterations = %d, expected %d", kdfIter, tt.kdfIter)
+			}
+
+			items, err := loadVault(t, tt.path, testVaultPassword, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 2 || items[0].GetTitle() != "GitHub" {
+				t.Errorf("unexpected items %v", items)
+			}
+		})
+	}
+
+	// vault encrypted with 320000 iterations is not opened with default ones
+	raw, err := os.ReadFile("testdata/kdfiter/vault.enpassdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p = filepath.Join(t.TempDir(), "vault.enpassdb")
+	if err = os.WriteFile(p, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadVault(t, p, testVaultPassword, "")
+	if err == nil || !strings.Contains(err.Error(), ErrInvalidVaultKey.Error()) {
+		t.Errorf("expected invalid key error without vault.json, got %v", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(filepath.Dir(p), vaultInfoFileName), []byte(`{"kdf_iter":0}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadVault(t, p, testVaultPassword, "")
+	if err == nil || !strings.Contains(err.Error(), "invalid kdf_iter") {
+		t.Errorf("expected invalid kdf_iter error, got %v", err)
+	}
+}
+
+func TestVaultSourceTruncated(t *testing.T) {
+	raw, err := os.ReadFile("testdata/vault/vault.enpassdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{0, 10, sqlcipherSaltSize, 4095, 4096 + 100} {
+		var p = filepath.Join(t.TempDir(), "vault.enpassdb")
+		if err = os.WriteFile(p, raw[:size], 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = loadVault(t, p, testVaultPassword, ""); err == nil {
+			t.Errorf("truncated vault of %d bytes is opened", size)
+		}
+	}
+}
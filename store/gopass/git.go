@@ -0,0 +1,124 @@
+package gopass
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/fsutil"
+	"github.com/gopasspw/gopass/pkg/gitconfig"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+// gopassSystemConfig - system wide gopass config of package maintainers
+var gopassSystemConfig = "/etc/gopass/config"
+
+// importStats - changes of single run, they are summarized in commit message
+type importStats struct {
+	created int
+	updated int
+	deleted int
+}
+
+func (s importStats) changed() bool {
+	return s.created+s.updated+s.deleted > 0
+}
+
+// gopassConfig - gopass config located the way gopass internal/config does:
+// system config unless GOPASS_CONFIG_NOSYSTEM is set, per-user config in gopass
+// config dir (GOPASS_HOMEDIR aware) or GOPASS_CONFIG relative to home, then env
+// overrides; gopass API writes root store path there on initialization
+func gopassConfig() *gitconfig.Configs {
+	cfg := gitconfig.New()
+	cfg.EnvPrefix = "GOPASS_CONFIG"
+	cfg.GlobalConfig = os.Getenv("GOPASS_CONFIG")
+	cfg.SystemConfig = gopassSystemConfig
+	cfg.Preset = gitconfig.NewFromMap(map[string]string{"core.autosync": "true"})
+	return cfg.LoadAll("")
+}
+
+// mountPaths - directories of root store ("") and mounts
+func mountPaths(cfg *gitconfig.Configs) map[string]string {
+	var out = map[string]string{"": fsutil.CleanPath(cfg.Get("mounts.path"))}
+	for _, m := range cfg.ListSubsections("mounts") {
+		out[m] = fsutil.CleanPath(cfg.Get("mounts." + m + ".path"))
+	}
+	return out
+}
+
+// mountOf - most specific mount of key as gopass resolves it
+func mountOf(mounts map[string]string, key string) string {
+	var names []string
+	for m := range mounts {
+		if m != "" {
+			names = append(names, m)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	for _, m := range names {
+		if strings.HasPrefix(key+"/", m+"/") {
+			return m
+		}
+	}
+	return ""
+}
+
+func runGit(ctx context.Context, dir string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("git %s: %s: %s", args[0], err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// commitTree - commits changes under paths of git working tree in one commit;
+// returns false if store is not git repository or nothing has changed
+func commitTree(ctx context.Context, dir string, paths []string, msg string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return false, nil
+	}
+
+	var pathspec []string
+	for _, p := range paths {
+		pathspec = append(pathspec, filepath.FromSlash(utils.FirstNonEmpty(p, ".")))
+	}
+
+	// gopass stages written secrets, removed ones are staged here
+	err := runGit(ctx, dir, append([]string{"add", "--all", "--"}, pathspec...)...)
+	if err != nil {
+		return false, err
+	}
+
+	if runGit(ctx, dir, append([]string{"diff", "--cached", "--quiet", "--"}, pathspec...)...) == nil {
+		return false, nil
+	}
+
+	err = runGit(ctx, dir, append([]string{"commit", "--quiet", "-m", msg, "--"}, pathspec...)...)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// hasRemote - checks whether git working tree has any remote
+func hasRemote(ctx context.Context, dir string) bool {
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "remote").Output()
+	return err == nil && strings.TrimSpace(string(out)) != ""
+}
+
+// pushTree - pushes git working tree to its default remote
+func pushTree(ctx context.Context, dir string) error {
+	if !hasRemote(ctx, dir) {
+		return fmt.Errorf("git repository '%s' has no remote", dir)
+	}
+	return runGit(ctx, dir, "push", "--quiet")
+}
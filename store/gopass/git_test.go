@@ -0,0 +1,93 @@
+package gopass
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGopassConfig(t *testing.T) {
+	var home = t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", home)
+	t.Setenv("GOPASS_CONFIG", "")
+	t.Setenv("GOPASS_CONFIG_NOSYSTEM", "true")
+
+	if v := gopassConfig().Get("core.autosync"); v != "true" {
+		t.Errorf("autosync without config = %q, expected gopass default true", v)
+	}
+
+	var dir = filepath.Join(home, ".config", "gopass")
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "config"), []byte(`[core]
+	autosync = false
+[mounts]
+	path = /stores/root
+[mounts "team"]
+	path = /stores/team
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg = gopassConfig()
+	if v := cfg.Get("core.autosync"); v != "false" {
+		t.Errorf("autosync = %q, expected false", v)
+	}
+
+	var expected = map[string]string{"": "/stores/root", "team": "/stores/team"}
+	if mounts := mountPaths(cfg); !reflect.DeepEqual(mounts, expected) {
+		t.Errorf("mountPaths = %v, expected %v", mounts, expected)
+	}
+}
+
+func TestGopassConfigLocations(t *testing.T) {
+	var home = t.TempDir()
+	t.Setenv("GOPASS_HOMEDIR", home)
+	t.Setenv("GOPASS_CONFIG", "")
+	t.Setenv("GOPASS_CONFIG_NOSYSTEM", "")
+
+	var writeConfig = func(p, storePath string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("[mounts]\n\tpath = "+storePath+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(p string) { gopassSystemConfig = p }(gopassSystemConfig)
+	gopassSystemConfig = filepath.Join(home, "etc", "config")
+	writeConfig(gopassSystemConfig, "/stores/system")
+	if v := gopassConfig().Get("mounts.path"); v != "/stores/system" {
+		t.Errorf("system config: mounts.path = %q", v)
+	}
+
+	t.Setenv("GOPASS_CONFIG_NOSYSTEM", "true")
+	if v := gopassConfig().Get("mounts.path"); v != "" {
+		t.Errorf("disabled system config: mounts.path = %q", v)
+	}
+
+	// GOPASS_CONFIG is relative to home and used if gopass config dir has no config
+	t.Setenv("GOPASS_CONFIG", "custom.cfg")
+	writeConfig(filepath.Join(home, "custom.cfg"), "/stores/custom")
+	if v := gopassConfig().Get("mounts.path"); v != "/stores/custom" {
+		t.Errorf("GOPASS_CONFIG: mounts.path = %q", v)
+	}
+
+	writeConfig(filepath.Join(home, ".config", "gopass", "config"), "/stores/user")
+	if v := gopassConfig().Get("mounts.path"); v != "/stores/user" {
+		t.Errorf("user config: mounts.path = %q", v)
+	}
+
+	t.Setenv("GOPASS_CONFIG_CONFIG_COUNT", "1")
+	t.Setenv("GOPASS_CONFIG_CONFIG_KEY_0", "mounts.path")
+	t.Setenv("GOPASS_CONFIG_CONFIG_VALUE_0", "/stores/env")
+	if v := gopassConfig().Get("mounts.path"); v != "/stores/env" {
+		t.Errorf("env override: mounts.path = %q", v)
+	}
+}
@@ -8,11 +8,15 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
+	"github.com/gopasspw/gopass/pkg/ctxutil"
 	"github.com/gopasspw/gopass/pkg/gopass"
 	"github.com/gopasspw/gopass/pkg/gopass/api"
 	"github.com/gopasspw/gopass/pkg/gopass/secrets"
 	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/store/attachment"
+	"github.com/revengel/enpass2gopass/store/secretdir"
 	"github.com/revengel/enpass2gopass/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -22,15 +26,31 @@ var (
 	ErrNotFound = fmt.Errorf("entry is not in the password store")
 )
 
+const (
+	// FormatAKV - key-value lines, multiline fields are appended after "---" marker
+	FormatAKV = "akv"
+	// FormatYAML - password line followed by yaml document
+	FormatYAML = "yaml"
+)
+
 // Gopass -
 type Gopass struct {
 	ctx            context.Context
 	api            *api.Gopass
 	prefix         string
+	format         string
+	routes         []route
+	targets        []string
+	mounts         map[string]string
 	uniqueKeys     *utils.UniqueStrings
 	uniquePrefixes *utils.UniqueStrings
-	dryrun         bool
-	logger         *logrus.Logger
+	push           bool
+	// autosync - push is taken from gopass config, stores without remote are not pushed
+	autosync bool
+	source   string
+	stats    map[string]*importStats
+	dryrun   bool
+	logger   *logrus.Logger
 }
 
 // Get -
@@ -70,11 +90,110 @@ func (g Gopass) remove(p string) error {
 	return g.api.Remove(g.ctx, p)
 }
 
-// Close -
+// Close - commits changes of run in one commit per mount
 func (g *Gopass) Close() error {
+	err := g.api.Close(g.ctx)
+	if err != nil {
+		return err
+	}
+
+	if g.dryrun {
+		return nil
+	}
+
+	var order []string
+	var targets = make(map[string][]string)
+	for _, t := range g.targets {
+		if !g.stats[t].changed() {
+			continue
+		}
+
+		var mount = mountOf(g.mounts, t)
+		if targets[mount] == nil {
+			order = append(order, mount)
+		}
+		targets[mount] = append(targets[mount], t)
+	}
+
+	for _, mount := range order {
+		err = g.commitMount(mount, targets[mount])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Discard - releases gopass api, changes are not committed because api
+// does not commit them itself
+func (g *Gopass) Discard() error {
 	return g.api.Close(g.ctx)
 }
 
+// commitMount - commits targets of single mount and pushes them if required
+func (g Gopass) commitMount(mount string, targets []string) error {
+	var dir = g.mounts[mount]
+	var paths []string
+	for _, t := range targets {
+		paths = append(paths, strings.TrimPrefix(strings.TrimPrefix(t, mount), "/"))
+	}
+
+	committed, err := commitTree(g.ctx, dir, paths, g.commitMessage(targets))
+	if err != nil {
+		return fmt.Errorf("cannot commit gopass store changes: %s", err.Error())
+	}
+
+	var l = g.logger.WithField("path", dir)
+	if !committed {
+		l.Debug("gopass store has nothing to commit")
+		return nil
+	}
+	l.Info("gopass store changes have been committed")
+
+	if !g.push {
+		return nil
+	}
+
+	if g.autosync && !hasRemote(g.ctx, dir) {
+		l.Debug("gopass store has no git remote, changes are not pushed")
+		return nil
+	}
+
+	err = pushTree(g.ctx, dir)
+	if err != nil {
+		return fmt.Errorf("cannot push gopass store changes: %s", err.Error())
+	}
+	l.Info("gopass store changes have been pushed")
+	return nil
+}
+
+// commitMessage - summary of run
+func (g Gopass) commitMessage(targets []string) string {
+	var total importStats
+	for _, t := range targets {
+		total.created += g.stats[t].created
+		total.updated += g.stats[t].updated
+		total.deleted += g.stats[t].deleted
+	}
+
+	var msg = fmt.Sprintf("Import secrets into %s: %d created, %d updated, %d deleted",
+		strings.Join(targets, ", "), total.created, total.updated, total.deleted)
+	if g.source != "" {
+		msg += "\n\n" + g.source
+	}
+	return msg
+}
+
+// targetOf - most specific target prefix of key, empty if key is not owned by run
+func (g Gopass) targetOf(key string) (out string) {
+	for _, t := range g.targets {
+		if strings.HasPrefix(key, t+"/") && len(t) > len(out) {
+			out = t
+		}
+	}
+	return
+}
+
 // Diff -
 func (g Gopass) diff(a, b gopass.Byter) bool {
 	ahash := utils.GetHashFromBytes(a.Bytes())
@@ -121,20 +240,28 @@ func (g Gopass) saveSecret(s gopass.Byter, p string) (bool, error) {
 		return false, err
 	}
 
+	if st := g.stats[g.targetOf(p)]; st != nil && rSec == nil {
+		st.created++
+	} else if st != nil {
+		st.updated++
+	}
+
 	l.Info("secret has been updated")
 	return true, nil
 }
 
-// Cleanup -
+// Cleanup - removes keys under targets of run which were not saved,
+// keys of other mounts nested under target are kept
 func (g Gopass) Cleanup() (bool, error) {
 	var deletesCount = 0
-	ll, err := g.list(`^` + g.prefix + `/`)
+	ll, err := g.list("")
 	if err != nil {
 		return false, err
 	}
 
 	for _, k := range ll {
-		if g.uniqueKeys.Has(k) {
+		var t = g.targetOf(k)
+		if t == "" || g.uniqueKeys.Has(k) || mountOf(g.mounts, k) != mountOf(g.mounts, t) {
 			continue
 		}
 
@@ -153,92 +280,99 @@ func (g Gopass) Cleanup() (bool, error) {
 		}
 
 		deletesCount++
+		g.stats[t].deleted++
 	}
 
 	return deletesCount > 0, nil
 }
 
-func (g Gopass) getMainSecretPath(p string) string {
-	return filepath.Join(g.prefix, p, "data")
+// route - target prefix of item, first matching route wins
+func (g Gopass) route(fields []field.FieldInterface, p string) string {
+	for _, r := range g.routes {
+		if r.match(fields, p) {
+			return r.target()
+		}
+	}
+	return g.prefix
 }
 
-func (g Gopass) getAttachmentSecretPath(p, attachmentName string) string {
-	return filepath.Join(g.prefix, p, "attachments", attachmentName)
+// yamlSecret - password on first line and other fields as yaml document,
+// multiline values become block scalars, tags and repeated keys become lists
+func yamlSecret(fields []field.FieldInterface) (gopass.Byter, error) {
+	var secret = &secrets.YAML{}
+	var values = make(map[string][]string)
+	var lists = make(map[string]bool)
+	for _, f := range fields {
+		var k, v = f.GetKey(), f.GetValueString()
+		switch {
+		case v == "", f.IsType(field.SecretIDField):
+			continue
+		case f.IsType(field.SecretPasswordField) && secret.Password() == "":
+			secret.SetPassword(v)
+		case f.IsType(field.SecretTagsField):
+			var tags = utils.ParseTags(v)
+			if len(tags) == 0 {
+				continue
+			}
+			values[k] = append(values[k], tags...)
+			lists[k] = true
+		default:
+			values[k] = append(values[k], v)
+		}
+	}
+
+	// gopass parses secret without yaml document as plain text
+	if len(values) == 0 {
+		return secretdir.AKVSecret(fields)
+	}
+
+	for k, v := range values {
+		var err error
+		if len(v) == 1 && !lists[k] {
+			err = secret.Set(k, v[0])
+		} else {
+			err = secret.Set(k, v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return secret, nil
 }
 
 // Save -
 func (g Gopass) Save(fields []field.FieldInterface, p string) (bool, error) {
 	var err error
 	var out bool
-	p = g.uniquePrefixes.Unique(p)
-	var keyPath = g.getMainSecretPath(p)
+	p = g.uniquePrefixes.Unique(filepath.Join(g.route(fields, p), p))
+	var keyPath = secretdir.MainPath("", p)
 
 	// create gopass secrets
-	var mainSecret = secrets.NewAKV()
 	var attachments = make(map[string]*secrets.AKV)
-	var multilineFields []field.FieldInterface
+	var secretFields []field.FieldInterface
 	for _, f := range fields {
-		switch f.GetType() {
-		case field.SecretAttachmentField:
-			// create separate secrets for attachments
-			var secret = secrets.NewAKV()
-			err = secret.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", f.GetKey()))
-			if err != nil {
-				return false, err
-			}
-
-			err = secret.Set("Content-Transfer-Encoding", "Base64")
-			if err != nil {
-				return false, err
-			}
-
-			_, err = secret.Write(f.GetValue())
-			if err != nil {
-				return false, err
-			}
-
-			attachments[f.GetKey()] = secret
-		case field.SecretPasswordField:
-			// SetPassword -
-			if mainSecret.Password() == "" {
-				mainSecret.SetPassword(f.GetValueString())
-				continue
-			}
-			fallthrough
-		default:
-			if f.IsMultiline() {
-				multilineFields = append(multilineFields, f)
-				continue
-			}
-
-			if v := f.GetValueString(); v == "" {
-				continue
-			}
-
-			err = mainSecret.Set(f.GetKey(), f.GetValueString())
-			if err != nil {
-				return false, nil
-			}
-		}
-	}
-
-	// writing multiline fields in end of decret
-	if len(multilineFields) > 0 {
-		var data string
-		for _, f := range multilineFields {
-			if f.GetValueString() == "" {
-				continue
-			}
-			if data == "" {
-				data = "---\n"
-			}
-			data += fmt.Sprintf("%s\n\n%s\n", f.GetKey(), f.GetValueString())
+		if !f.IsType(field.SecretAttachmentField) {
+			secretFields = append(secretFields, f)
+			continue
 		}
 
-		_, err = mainSecret.Write([]byte(data))
+		// create separate secrets for attachments
+		secret, err := attachment.NewSecret(f.GetKey(), f.GetValue())
 		if err != nil {
-			return false, nil
+			return false, err
 		}
+
+		attachments[f.GetKey()] = secret
+	}
+
+	var mainSecret gopass.Byter
+	if g.format == FormatYAML {
+		mainSecret, err = yamlSecret(secretFields)
+	} else {
+		mainSecret, err = secretdir.AKVSecret(secretFields)
+	}
+	if err != nil {
+		return false, err
 	}
 
 	same, err := g.saveSecret(mainSecret, keyPath)
@@ -249,7 +383,7 @@ func (g Gopass) Save(fields []field.FieldInterface, p string) (bool, error) {
 	out = out || same
 
 	for attachName, secret := range attachments {
-		var keyPath = g.getAttachmentSecretPath(p, attachName)
+		var keyPath = secretdir.AttachmentPath("", p, attachName)
 		same, err := g.saveSecret(secret, keyPath)
 		if err != nil {
 			return out, err
@@ -261,25 +395,67 @@ func (g Gopass) Save(fields []field.FieldInterface, p string) (bool, error) {
 	return out, nil
 }
 
-// NewStore -
-func NewStore(ctx context.Context, prefix string, dryrun bool, logger *logrus.Logger) (g *Gopass, err error) {
-	var gp *api.Gopass
-	gp, err = api.New(ctx)
-	if err != nil {
-		return g, fmt.Errorf("failed to initialize gopass API: %s", err.Error())
-	}
-
+// NewStore - routes send matching items into prefixes of other mounts;
+// changes are pushed if gopass autosync is enabled when push is nil
+func NewStore(ctx context.Context, prefix, format string, routes []string, push *bool, source string, dryrun bool, logger *logrus.Logger) (g *Gopass, err error) {
 	if prefix == "" {
 		prefix = "enpass"
 	}
 
-	return &Gopass{
-		ctx:            ctx,
-		api:            gp,
+	format = utils.FirstNonEmpty(format, FormatAKV)
+	if format != FormatAKV && format != FormatYAML {
+		return nil, fmt.Errorf("invalid gopass secret format: '%s'", format)
+	}
+
+	g = &Gopass{
 		prefix:         prefix,
+		format:         format,
+		targets:        []string{prefix},
 		uniqueKeys:     utils.NewUniqueStrings(logger),
 		uniquePrefixes: utils.NewUniqueStrings(logger),
+		source:         source,
+		stats:          map[string]*importStats{prefix: {}},
 		dryrun:         dryrun,
 		logger:         logger,
-	}, nil
+	}
+
+	for _, s := range routes {
+		r, err := parseRoute(s, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		g.routes = append(g.routes, r)
+		if g.stats[r.target()] == nil {
+			g.targets = append(g.targets, r.target())
+			g.stats[r.target()] = &importStats{}
+		}
+	}
+
+	// secrets are committed once in Close
+	g.ctx = ctxutil.WithGitCommit(ctx, false)
+	g.api, err = api.New(g.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize gopass API: %s", err.Error())
+	}
+
+	// config is read after gopass API has initialized it
+	var cfg = gopassConfig()
+	g.mounts = mountPaths(cfg)
+	if push != nil {
+		g.push = *push
+	} else {
+		g.push, g.autosync = cfg.Get("core.autosync") == "true", true
+	}
+	for _, r := range g.routes {
+		if _, ok := g.mounts[r.mount]; !ok {
+			return nil, fmt.Errorf("gopass mount '%s' does not exist", r.mount)
+		}
+
+		if m := mountOf(g.mounts, r.target()); m != r.mount {
+			return nil, fmt.Errorf("gopass route target '%s' belongs to mount '%s'", r.target(), m)
+		}
+	}
+
+	return g, nil
 }
@@ -0,0 +1,36 @@
+package gopass
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/store/secretdir"
+)
+
+func TestSecretsSkipIDField(t *testing.T) {
+	var fields = []field.FieldInterface{
+		field.NewPasswordField("", "pass"),
+		field.NewIDField("enpass_uuid", "0b3c7f6e"),
+		field.NewUsernameField("", "user"),
+	}
+
+	akv, err := secretdir.AKVSecret(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yaml, err := yamlSecret(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, out := range map[string]string{"akv": string(akv.Bytes()), "yaml": string(yaml.Bytes())} {
+		if strings.Contains(out, "enpass_uuid") {
+			t.Errorf("%s secret contains source id:\n%s", name, out)
+		}
+		if !strings.HasPrefix(out, "pass\n") || !strings.Contains(out, "user") {
+			t.Errorf("%s secret lost fields:\n%s", name, out)
+		}
+	}
+}
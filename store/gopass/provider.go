@@ -0,0 +1,61 @@
+package gopass
+
+import "github.com/revengel/enpass2gopass/store"
+
+const (
+	// SourceName -
+	SourceName = "gopassSource"
+	// DestinationName -
+	DestinationName = "gopassDestination"
+)
+
+func init() {
+	store.RegisterSource(store.SourceProvider{
+		Provider: store.Provider{
+			Name:        SourceName,
+			Description: "gopass store written by gopass destination",
+			FlagPrefix:  "source-gopass",
+			Options: []store.Option{
+				store.StringOption("prefix", "", "source gopass prefix, whole store if empty"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreSource, error) {
+			s, err := NewGopassSource(env.Ctx, opts.String("prefix"), env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	})
+
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        DestinationName,
+			Description: "gopass store configured for current user",
+			FlagPrefix:  "destination-gopass",
+			Options: []store.Option{
+				store.StringOption("format", FormatAKV, "gopass secret format: akv (key-value lines) or yaml (password line and yaml document)"),
+				store.StringsOption("route", nil, "route matching items into gopass mount as <folder|category|path>:<glob>=<mount>:<prefix>, empty mount is root store, empty prefix is --prefix, first matching route wins"),
+				store.BoolOption("push", false, "push gopass store git remote once after import, secrets are committed in one commit; gopass core.autosync is used if not set"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			var push *bool
+			if opts.Changed("push") {
+				var v = opts.Bool("push")
+				push = &v
+			}
+
+			source, err := env.SourceSummary()
+			if err != nil {
+				return nil, err
+			}
+
+			st, err := NewStore(env.Ctx, env.Prefix, opts.String("format"), opts.Strings("route"), push, source, env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
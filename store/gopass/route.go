@@ -0,0 +1,108 @@
+package gopass
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+const (
+	routeFolder   = "folder"
+	routeCategory = "category"
+	routePath     = "path"
+)
+
+// route - rule which sends matching items into prefix of gopass mount,
+// it is written as <folder|category|path>:<glob>=<mount>:<prefix>
+type route struct {
+	kind    string
+	pattern string
+	mount   string
+	prefix  string
+}
+
+// target - gopass path of route prefix
+func (r route) target() string {
+	return path.Join(r.mount, r.prefix)
+}
+
+// parseRoute - empty mount is root store, empty prefix is replaced with default one
+func parseRoute(s, defaultPrefix string) (r route, err error) {
+	var i = strings.LastIndex(s, "=")
+	if i < 0 {
+		return r, fmt.Errorf("invalid gopass route '%s': target is not set", s)
+	}
+
+	var matcher, target = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	var found bool
+	r.kind, r.pattern, found = strings.Cut(matcher, ":")
+	if !found || r.pattern == "" {
+		return r, fmt.Errorf("invalid gopass route '%s': matcher must be <folder|category|path>:<glob>", s)
+	}
+
+	switch r.kind {
+	case routeFolder, routeCategory, routePath:
+	default:
+		return r, fmt.Errorf("invalid gopass route '%s': unknown matcher '%s'", s, r.kind)
+	}
+
+	if _, err = path.Match(r.pattern, ""); err != nil {
+		return r, fmt.Errorf("invalid gopass route '%s': %s", s, err.Error())
+	}
+
+	r.mount, r.prefix, found = strings.Cut(target, ":")
+	if !found {
+		return r, fmt.Errorf("invalid gopass route '%s': target must be <mount>:<prefix>", s)
+	}
+
+	r.mount, r.prefix = strings.Trim(r.mount, "/"), strings.Trim(r.prefix, "/")
+	if r.prefix == "" {
+		r.prefix = defaultPrefix
+	}
+	return r, nil
+}
+
+// matchGlob - case insensitive glob match, plain names are compared
+// transliterated as sources usually export them so
+func matchGlob(pattern, v string) bool {
+	if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(v)); ok {
+		return true
+	}
+	return !strings.ContainsAny(pattern, `*?[\`) && utils.Transliterate(pattern) == utils.Transliterate(v)
+}
+
+// match - folders are taken from tags field, category from category field
+// and path glob matches secret path or any of its parents
+func (r route) match(fields []field.FieldInterface, p string) bool {
+	switch r.kind {
+	case routeFolder:
+		for _, f := range fields {
+			if !f.IsType(field.SecretTagsField) {
+				continue
+			}
+			for _, t := range utils.ParseTags(f.GetValueString()) {
+				if matchGlob(r.pattern, t) {
+					return true
+				}
+			}
+		}
+	case routeCategory:
+		for _, f := range fields {
+			if f.GetKey() == "category" && matchGlob(r.pattern, f.GetValueString()) {
+				return true
+			}
+		}
+	case routePath:
+		var parts = strings.Split(filepath.ToSlash(p), "/")
+		for n := range parts {
+			if matchGlob(r.pattern, strings.Join(parts[:n+1], "/")) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+package gopass
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/revengel/enpass2gopass/store/attachment"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	mainSecretName = "data"
+	attachmentsDir = "attachments"
+)
+
+// idKeys - id fields written by sources of this tool
+var idKeys = []string{"enpass_uuid", "keepass_uuid", "bitwarden_id", "onepassword_uuid", "csv_id"}
+
+// sensitiveKeyRe - gopass secrets have no field types, so sensitivity is guessed by key
+var sensitiveKeyRe = regexp.MustCompile(`(?i)(password|passphrase|secret|token|pin|otp|cvc|cvv|private)`)
+
+var urlKeyRe = regexp.MustCompile(`(?i)^url(_\d+)?$`)
+
+// keyValue - header line of AKV secret
+type keyValue struct {
+	key   string
+	value string
+}
+
+// secretReader - part of gopass api used by source
+type secretReader interface {
+	List(ctx context.Context) ([]string, error)
+	Get(ctx context.Context, name, revision string) (gopass.Secret, error)
+	Close(ctx context.Context) error
+}
+
+// GopassSource - reads secrets under prefix of gopass store
+type GopassSource struct {
+	ctx    context.Context
+	api    secretReader
+	prefix string
+	logger *logrus.Logger
+}
+
+// SourceItem - gopass secret with its attachments
+type SourceItem struct {
+	path        string
+	secret      gopass.Secret
+	attachments []field.FieldInterface
+}
+
+// GetSecretPath -
+func (i SourceItem) GetSecretPath() (string, error) {
+	return i.path, nil
+}
+
+// parseAKV - splits raw secret into password, header key-value lines and body;
+// gopass AKV parser drops lines of body which look like key-value pairs
+func parseAKV(raw string) (password string, kvs []keyValue, body string) {
+	var lines = strings.Split(raw, "\n")
+	password = strings.TrimSpace(lines[0])
+	for n := 1; n < len(lines); n++ {
+		var line = lines[n]
+		if strings.TrimSpace(line) == "---" {
+			return password, kvs, strings.Join(lines[n+1:], "\n")
+		}
+
+		k, v, found := strings.Cut(line, ": ")
+		if !found {
+			return password, kvs, strings.Join(lines[n:], "\n")
+		}
+
+		kvs = append(kvs, keyValue{key: strings.TrimSpace(k), value: strings.TrimSpace(v)})
+	}
+	return
+}
+
+func isMultilineKey(lines []string, n int) bool {
+	var l = lines[n]
+	return l != "" && len(l) <= 64 && !strings.Contains(l, ": ") && strings.TrimSpace(l) == l &&
+		n+1 < len(lines) && lines[n+1] == ""
+}
+
+// parseMultiline - parses body written by destination as "key\n\nvalue\n" blocks,
+// other bodies are returned as single note field
+func parseMultiline(body string) (out []field.FieldInterface) {
+	body = strings.TrimRight(body, "\n")
+	if strings.TrimSpace(body) == "" {
+		return nil
+	}
+
+	var lines = strings.Split(body, "\n")
+	if !isMultilineKey(lines, 0) {
+		return []field.FieldInterface{field.NewSimpleField("note", []byte(body), true, false)}
+	}
+
+	var key string
+	var value []string
+	var flush = func() {
+		if key != "" {
+			out = append(out, field.NewSimpleField(key, []byte(strings.Join(value, "\n")), true, false))
+		}
+	}
+
+	for n := 0; n < len(lines); n++ {
+		if isMultilineKey(lines, n) {
+			flush()
+			key, value = lines[n], nil
+			n++
+			continue
+		}
+		value = append(value, lines[n])
+	}
+	flush()
+
+	return
+}
+
+func keyField(k, v string) field.FieldInterface {
+	switch strings.ToLower(k) {
+	case "title":
+		return field.NewTitleField(k, v)
+	case "username", "login", "user":
+		return field.NewUsernameField(k, v)
+	case "password":
+		return field.NewPasswordField(k, v)
+	case "tags":
+		return field.NewTagsField(k, v)
+	case "otpauth":
+		// gopass accepts uri without scheme as well
+		if strings.HasPrefix(v, "//") {
+			v = "otpauth:" + v
+		}
+		return field.NewOTPField(k, v)
+	}
+
+	switch {
+	case urlKeyRe.MatchString(k):
+		return field.NewUrlField(strings.ToLower(k), v)
+	case strings.ToLower(k) == k && utils.InList(idKeys, k):
+		return field.NewIDField(k, v)
+	}
+
+	return field.NewSimpleField(k, []byte(v), strings.Contains(v, "\n"), sensitiveKeyRe.MatchString(k))
+}
+
+// yamlDocument - yaml part of secret as serialized by gopass
+func yamlDocument(y *secrets.YAML) (out map[string]interface{}) {
+	var doc = strings.TrimPrefix(string(y.Bytes()), y.Password())
+	if b := y.Body(); b != "" {
+		doc = strings.TrimPrefix(doc, "\n"+b)
+	}
+	doc = strings.TrimPrefix(strings.TrimPrefix(doc, "\n"), "---\n")
+
+	_ = yaml.Unmarshal([]byte(doc), &out)
+	return
+}
+
+// yamlValues - lists are split into values, except tags which are kept
+// as single "[a, b]" value; nested documents are kept as yaml text
+func yamlValues(k string, v interface{}) (out []string) {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []interface{}:
+		for _, e := range v {
+			out = append(out, yamlValues(k, e)...)
+		}
+		if strings.EqualFold(k, "tags") {
+			return []string{fmt.Sprintf("[%s]", strings.Join(out, ", "))}
+		}
+		return out
+	case map[string]interface{}:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		return []string{strings.TrimSuffix(string(data), "\n")}
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+// yamlKeyValues - values of yaml secret by sorted keys
+func yamlKeyValues(y *secrets.YAML) (kvs []keyValue) {
+	var doc = yamlDocument(y)
+	for _, k := range y.Keys() {
+		for _, v := range yamlValues(k, doc[k]) {
+			kvs = append(kvs, keyValue{key: k, value: v})
+		}
+	}
+	return
+}
+
+// GetFields -
+func (i SourceItem) GetFields() (out []field.FieldInterface, err error) {
+	var password, body string
+	var kvs []keyValue
+	if y, ok := i.secret.(*secrets.YAML); ok {
+		password, body = y.Password(), y.Body()
+		kvs = yamlKeyValues(y)
+	} else {
+		password, kvs, body = parseAKV(string(i.secret.Bytes()))
+	}
+
+	if password != "" {
+		out = append(out, field.NewPasswordField("", password))
+	}
+
+	for _, kv := range kvs {
+		if kv.value == "" {
+			continue
+		}
+		out = append(out, keyField(kv.key, kv.value))
+	}
+
+	out = append(out, parseMultiline(body)...)
+	out = append(out, i.attachments...)
+	return
+}
+
+// attachmentField - decodes attachment secret written by destination
+func attachmentField(name string, sec gopass.Secret) (field.FieldInterface, bool, error) {
+	filename, data, ok, err := attachment.Decode(name, sec)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	return field.NewAttachmentField(filename, data), true, nil
+}
+
+// LoadData -
+func (s GopassSource) LoadData() (o []store.StoreSourceItem, err error) {
+	keys, err := s.api.List(s.ctx)
+	if err != nil {
+		return
+	}
+
+	var prefix = strings.Trim(s.prefix, "/")
+	var items = make(map[string]*SourceItem)
+	var order []string
+	var attachments = make(map[string][]string)
+	for _, k := range keys {
+		var rel = k
+		if prefix != "" {
+			if !strings.HasPrefix(k, prefix+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(k, prefix+"/")
+		}
+
+		if owner, name, found := strings.Cut(rel, "/"+attachmentsDir+"/"); found && !strings.Contains(name, "/") {
+			attachments[owner] = append(attachments[owner], k)
+			continue
+		}
+
+		sec, err := s.api.Get(s.ctx, k, "latest")
+		if err != nil {
+			return nil, fmt.Errorf("cannot read gopass secret '%s': %s", k, err.Error())
+		}
+
+		var p = strings.TrimSuffix(rel, "/"+mainSecretName)
+		if p == "" || p == mainSecretName {
+			return nil, fmt.Errorf("invalid gopass secret path: '%s'", k)
+		}
+
+		items[p] = &SourceItem{path: p, secret: sec}
+		order = append(order, p)
+	}
+
+	// owners are sorted to keep order of orphaned attachments stable
+	var owners = make([]string, 0, len(attachments))
+	for owner := range attachments {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	for _, owner := range owners {
+		for _, k := range attachments[owner] {
+			sec, err := s.api.Get(s.ctx, k, "latest")
+			if err != nil {
+				return nil, fmt.Errorf("cannot read gopass secret '%s': %s", k, err.Error())
+			}
+
+			item, ok := items[owner]
+			f, isAttachment, err := attachmentField(path.Base(k), sec)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read gopass secret '%s': %s", k, err.Error())
+			}
+			if !ok || !isAttachment {
+				// not an attachment of known secret, import as regular secret
+				var p = strings.TrimPrefix(strings.TrimPrefix(k, prefix), "/")
+				items[p] = &SourceItem{path: p, secret: sec}
+				order = append(order, p)
+				continue
+			}
+
+			item.attachments = append(item.attachments, f)
+		}
+	}
+
+	for _, p := range order {
+		o = append(o, *items[p])
+	}
+
+	s.logger.WithField("count", len(o)).Debug("gopass secrets have been loaded")
+	return o, nil
+}
+
+// Close -
+func (s *GopassSource) Close() error {
+	return s.api.Close(s.ctx)
+}
+
+// NewGopassSource -
+func NewGopassSource(ctx context.Context, prefix string, logger *logrus.Logger) (s *GopassSource, err error) {
+	gp, err := api.New(ctx)
+	if err != nil {
+		return s, fmt.Errorf("failed to initialize gopass API: %s", err.Error())
+	}
+
+	return &GopassSource{
+		ctx:    ctx,
+		api:    gp,
+		prefix: prefix,
+		logger: logger,
+	}, nil
+}
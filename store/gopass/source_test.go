@@ -0,0 +1,133 @@
+package gopass
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/store/attachment"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeReader - in-memory gopass store
+type fakeReader map[string]gopass.Secret
+
+func (r fakeReader) List(ctx context.Context) ([]string, error) {
+	var keys []string
+	for k := range r {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (r fakeReader) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	sec, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("secret %s does not exist", name)
+	}
+	return sec, nil
+}
+
+func (r fakeReader) Close(ctx context.Context) error {
+	return nil
+}
+
+func testSource(secrets fakeReader, prefix string) *GopassSource {
+	var l = logrus.New()
+	l.SetOutput(io.Discard)
+	return &GopassSource{ctx: context.Background(), api: secrets, prefix: prefix, logger: l}
+}
+
+func attachmentSecret(t *testing.T, name, data string) gopass.Secret {
+	t.Helper()
+	sec, err := attachment.NewSecret(name, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sec
+}
+
+func TestGopassSourceAttachmentsOrder(t *testing.T) {
+	var main = secrets.NewAKV()
+	main.SetPassword("s3cr3t")
+	if err := main.Set("username", "octocat"); err != nil {
+		t.Fatal(err)
+	}
+
+	var store = fakeReader{
+		"import/known/data":                main,
+		"import/known/attachments/key.bin": attachmentSecret(t, "key.bin", "\x00\x01"),
+		"other/data":                       main,
+	}
+	for _, owner := range []string{"zeta", "alpha", "mid", "beta", "omega"} {
+		store["import/"+owner+"/attachments/file"] = attachmentSecret(t, "file", owner)
+	}
+
+	var want = "known alpha/attachments/file beta/attachments/file mid/attachments/file omega/attachments/file zeta/attachments/file"
+	for n := 0; n < 20; n++ {
+		items, err := testSource(store, "import").LoadData()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var paths []string
+		for _, item := range items {
+			p, _ := item.GetSecretPath()
+			paths = append(paths, p)
+		}
+		if got := strings.Join(paths, " "); got != want {
+			t.Fatalf("expected order %q, got %q", want, got)
+		}
+	}
+}
+
+func TestGopassSourceFields(t *testing.T) {
+	var main = secrets.NewAKV()
+	main.SetPassword("s3cr3t")
+	for k, v := range map[string]string{"username": "octocat", "url": "https://github.com", "enpass_uuid": "id-1"} {
+		if err := main.Set(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var store = fakeReader{
+		"import/known/data":                main,
+		"import/known/attachments/key.bin": attachmentSecret(t, "key.bin", "\x00\x01"),
+	}
+	items, err := testSource(store, "import").LoadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected one item, got %d", len(items))
+	}
+
+	fields, err := items[0].GetFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types = make(map[string]field.FieldType)
+	for _, f := range fields {
+		types[f.GetKey()] = f.GetType()
+	}
+
+	for k, typ := range map[string]field.FieldType{
+		"password":    field.SecretPasswordField,
+		"username":    field.SecretUsernameField,
+		"url":         field.SecretURLField,
+		"enpass_uuid": field.SecretIDField,
+		"key.bin":     field.SecretAttachmentField,
+	} {
+		if types[k] != typ {
+			t.Errorf("field %s: expected type %s, got %q", k, typ, types[k])
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package keepass
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"golang.org/x/crypto/argon2"
+)
+
+// gokeepasslib derives KDBX 4 keys with argon2d or AES-KDF only and treats any
+// other kdf as AES-KDF, for argon2id parameters (they have no rounds) its key is
+// plain sha256 of composite key. argon2id databases are re-keyed in memory:
+// header is kept as is, while header HMAC and payload are translated between
+// the key derived with argon2id and the one library derives.
+
+const (
+	argon2Version = 0x13
+	// kdbx4BlockSize - payload block size used by KeePass
+	kdbx4BlockSize = 1024 * 1024
+)
+
+var errTruncatedDatabase = errors.New("database is truncated")
+
+// argon2idKey - transformed keys of argon2id database
+type argon2idKey struct {
+	argon2id []byte
+	library  []byte
+}
+
+func isArgon2id(params *gokeepasslib.KdfParameters) bool {
+	return params != nil && bytes.Equal(params.UUID, kdfArgon2idUUID)
+}
+
+// newArgon2idKey - derives keys of database with kdf parameters, returns nil for other kdfs
+func newArgon2idKey(credentials *gokeepasslib.DBCredentials, params *gokeepasslib.KdfParameters) (*argon2idKey, error) {
+	if !isArgon2id(params) {
+		return nil, nil
+	}
+
+	switch {
+	case credentials == nil:
+		return nil, errors.New("credentials are not set")
+	case params.Version != argon2Version:
+		return nil, fmt.Errorf("argon2 version 0x%x is not supported", params.Version)
+	case len(params.SecretKey) > 0 || len(params.AssocData) > 0:
+		return nil, errors.New("argon2 secret key and associated data are not supported")
+	case params.Rounds > 0:
+		return nil, errors.New("argon2 parameters cannot have AES-KDF rounds")
+	case params.Iterations == 0 || params.Iterations > math.MaxUint32:
+		return nil, fmt.Errorf("invalid argon2 iterations: %d", params.Iterations)
+	case params.Parallelism == 0 || params.Parallelism > math.MaxUint8:
+		return nil, fmt.Errorf("invalid argon2 parallelism: %d", params.Parallelism)
+	case params.Memory < 1024 || params.Memory/1024 > math.MaxUint32:
+		return nil, fmt.Errorf("invalid argon2 memory: %d", params.Memory)
+	}
+
+	// composite key is built the way gokeepasslib does
+	var composite = sha256.New()
+	composite.Write(credentials.Passphrase)
+	composite.Write(credentials.Key)
+	composite.Write(credentials.Windows)
+	var key = composite.Sum(nil)
+
+	var library = sha256.Sum256(key)
+	return &argon2idKey{
+		argon2id: argon2.IDKey(key, params.Salt[:], uint32(params.Iterations),
+			uint32(params.Memory/1024), uint8(params.Parallelism), 32),
+		library: library[:],
+	}, nil
+}
+
+// decode - translates argon2id database into the one gokeepasslib can decode
+func (k *argon2idKey) decode(raw []byte) ([]byte, error) {
+	return rekey(raw, k.argon2id, k.library)
+}
+
+// encode - translates database encoded by gokeepasslib back to argon2id one
+func (k *argon2idKey) encode(raw []byte) ([]byte, error) {
+	return rekey(raw, k.library, k.argon2id)
+}
+
+// readKdbx4Header - splits KDBX 4 header into fields, header ends with field 0
+func readKdbx4Header(raw []byte) (header []byte, fields map[uint8][]byte, err error) {
+	if len(raw) < 12 || !bytes.Equal(raw[:4], gokeepasslib.BaseSignature[:]) ||
+		!bytes.Equal(raw[4:8], gokeepasslib.SecondarySignature[:]) {
+		return nil, nil, errors.New("file is not a keepass database")
+	}
+	if binary.LittleEndian.Uint16(raw[10:12]) != 4 {
+		return nil, nil, errors.New("database is not in KDBX 4 format")
+	}
+
+	fields = make(map[uint8][]byte)
+	var offset = 12
+	for {
+		if len(raw) < offset+5 {
+			return nil, nil, errTruncatedDatabase
+		}
+		var id = raw[offset]
+		var size = int(binary.LittleEndian.Uint32(raw[offset+1:]))
+		offset += 5
+		if size > len(raw)-offset {
+			return nil, nil, errTruncatedDatabase
+		}
+
+		fields[id] = raw[offset : offset+size]
+		offset += size
+		if id == 0 {
+			return raw[:offset], fields, nil
+		}
+	}
+}
+
+// kdfParameters - reads kdf parameters of KDBX 4 database, returns nil for
+// anything else and leaves reporting of malformed files to gokeepasslib
+func kdfParameters(raw []byte) *gokeepasslib.KdfParameters {
+	_, fields, err := readKdbx4Header(raw)
+	if err != nil {
+		return nil
+	}
+
+	// variant dictionary: version, then items of type, name and value
+	// terminated by type 0
+	var data = fields[11]
+	if len(data) < 2 || data[1] != 1 {
+		return nil
+	}
+
+	var params = new(gokeepasslib.KdfParameters)
+	var offset = 2
+	var next = func() []byte {
+		if len(data) < offset+4 {
+			return nil
+		}
+		var size = int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		if size > len(data)-offset {
+			return nil
+		}
+		offset += size
+		return data[offset-size : offset]
+	}
+
+	for offset < len(data) && data[offset] != 0 {
+		offset++
+		var name, value = next(), next()
+		if name == nil || value == nil {
+			return nil
+		}
+
+		switch {
+		case string(name) == "$UUID":
+			params.UUID = value
+		case string(name) == "S" && len(value) == 32:
+			copy(params.Salt[:], value)
+		case string(name) == "R" && len(value) == 8:
+			params.Rounds = binary.LittleEndian.Uint64(value)
+		case string(name) == "I" && len(value) == 8:
+			params.Iterations = binary.LittleEndian.Uint64(value)
+		case string(name) == "M" && len(value) == 8:
+			params.Memory = binary.LittleEndian.Uint64(value)
+		case string(name) == "P" && len(value) == 4:
+			params.Parallelism = binary.LittleEndian.Uint32(value)
+		case string(name) == "V" && len(value) == 4:
+			params.Version = binary.LittleEndian.Uint32(value)
+		case string(name) == "K":
+			params.SecretKey = value
+		case string(name) == "A":
+			params.AssocData = value
+		default:
+			return nil
+		}
+	}
+	return params
+}
+
+// headerHMAC - HMAC-SHA256 of KDBX 4 header, it is keyed like block with index 2^64-1
+func headerHMAC(header, seed, transformedKey []byte) []byte {
+	var base = sha512.New()
+	base.Write(seed)
+	base.Write(transformedKey)
+	base.Write([]byte{0x01})
+
+	var key = sha512.New()
+	key.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	key.Write(base.Sum(nil))
+
+	var mac = hmac.New(sha256.New, key.Sum(nil))
+	mac.Write(header)
+	return mac.Sum(nil)
+}
+
+// rekey - verifies KDBX 4 database with one transformed key and
+// re-encrypts it with another one, header is not changed
+func rekey(raw, from, to []byte) ([]byte, error) {
+	header, fields, err := readKdbx4Header(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var seed, iv = fields[4], fields[7]
+	if !bytes.Equal(fields[2], gokeepasslib.CipherAES) && !bytes.Equal(fields[2], gokeepasslib.CipherChaCha20) {
+		return nil, errors.New("database cipher is not supported, use AES or ChaCha20")
+	}
+
+	// header is followed by its sha256 and HMAC
+	var rest = raw[len(header):]
+	if len(rest) < 64 {
+		return nil, errTruncatedDatabase
+	}
+	if !hmac.Equal(headerHMAC(header, seed, from), rest[32:64]) {
+		return nil, errors.New("wrong credentials or corrupted database: header HMAC mismatch")
+	}
+
+	// payload is split into blocks of HMAC, size and data, terminated by empty block
+	var blocks = gokeepasslib.NewBlockHMACBuilder(seed, from)
+	var encrypted []byte
+	var offset int
+	for index := uint64(0); ; index++ {
+		var block = rest[64+offset:]
+		if len(block) < 36 {
+			return nil, errTruncatedDatabase
+		}
+		var size = binary.LittleEndian.Uint32(block[32:36])
+		if int64(size) > int64(len(block)-36) {
+			return nil, errTruncatedDatabase
+		}
+
+		var data = block[36 : 36+size]
+		if !hmac.Equal(blocks.BuildHMAC(index, size, data), block[:32]) {
+			return nil, fmt.Errorf("corrupted database: HMAC mismatch of block %d", index)
+		}
+		offset += 36 + int(size)
+		if size == 0 {
+			break
+		}
+		encrypted = append(encrypted, data...)
+	}
+	if len(iv) == aes.BlockSize && len(encrypted)%aes.BlockSize != 0 {
+		return nil, errors.New("corrupted database: payload is not aligned to AES block")
+	}
+
+	decrypter, err := gokeepasslib.NewEncrypterManager(masterKey(seed, from), iv)
+	if err != nil {
+		return nil, err
+	}
+	encrypter, err := gokeepasslib.NewEncrypterManager(masterKey(seed, to), iv)
+	if err != nil {
+		return nil, err
+	}
+	var payload = encrypter.Encrypt(decrypter.Decrypt(encrypted))
+
+	var out bytes.Buffer
+	out.Write(header)
+	out.Write(rest[:32])
+	out.Write(headerHMAC(header, seed, to))
+
+	blocks = gokeepasslib.NewBlockHMACBuilder(seed, to)
+	for index := uint64(0); ; index++ {
+		var data = payload[:min(len(payload), kdbx4BlockSize)]
+		payload = payload[len(data):]
+
+		var size [4]byte
+		binary.LittleEndian.PutUint32(size[:], uint32(len(data)))
+		out.Write(blocks.BuildHMAC(index, uint32(len(data)), data))
+		out.Write(size[:])
+		out.Write(data)
+		if len(data) == 0 {
+			return out.Bytes(), nil
+		}
+	}
+}
+
+func masterKey(seed, transformedKey []byte) []byte {
+	var h = sha256.New()
+	h.Write(seed)
+	h.Write(transformedKey)
+	return h.Sum(nil)
+}
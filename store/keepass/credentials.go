@@ -0,0 +1,21 @@
+package keepass
+
+import (
+	"errors"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// NewCredentials - builds database credentials from password, key file or both;
+// key file can be XML (v1/v2), 32 raw bytes, 64 hex chars or any file hashed with sha256
+func NewCredentials(password string, withPassword bool, keyFile string) (*gokeepasslib.DBCredentials, error) {
+	switch {
+	case withPassword && keyFile != "":
+		return gokeepasslib.NewPasswordAndKeyCredentials(password, keyFile)
+	case keyFile != "":
+		return gokeepasslib.NewKeyCredentials(keyFile)
+	case withPassword:
+		return gokeepasslib.NewPasswordCredentials(password), nil
+	}
+	return nil, errors.New("neither password nor key file is set")
+}
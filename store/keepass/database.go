@@ -0,0 +1,125 @@
+package keepass
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+const (
+	// KdfArgon2d - argon2d key derivation function
+	KdfArgon2d = "argon2d"
+	// KdfAES - AES-KDF key derivation function
+	KdfAES = "aes"
+	// KdfArgon2id - argon2id key derivation function (KeePassXC default)
+	KdfArgon2id = "argon2id"
+)
+
+// kdfArgon2idUUID - KDF parameters UUID of argon2id databases (KeePassXC default)
+var kdfArgon2idUUID = []byte{0x9E, 0x29, 0x8B, 0x19, 0x56, 0xDB, 0x47, 0x73, 0xB2, 0x3D, 0xFC, 0x3E, 0xC6, 0xF0, 0xA1, 0xE6}
+
+// DatabaseOptions - parameters of newly created database
+type DatabaseOptions struct {
+	RootGroupName string
+	// Kdf - key derivation function: argon2d, argon2id or aes
+	Kdf string
+	// Iterations - argon2 iterations or AES-KDF transform rounds
+	Iterations uint64
+	// Memory - argon2 memory in MiB
+	Memory uint64
+	// Parallelism - argon2 lanes count
+	Parallelism uint32
+	// Kdbx3 - create KDBX 3.1 database instead of KDBX 4
+	Kdbx3 bool
+}
+
+// NewDatabaseOptions - returns options with KeePassXC-like defaults
+func NewDatabaseOptions() DatabaseOptions {
+	return DatabaseOptions{
+		RootGroupName: "Root",
+		Kdf:           KdfArgon2d,
+		Iterations:    10,
+		Memory:        64,
+		Parallelism:   2,
+	}
+}
+
+func (o DatabaseOptions) validate() error {
+	switch o.Kdf {
+	case KdfArgon2d, KdfArgon2id:
+		if o.Kdbx3 {
+			return fmt.Errorf("kdf '%s' is not supported by KDBX 3.1, use '%s'", o.Kdf, KdfAES)
+		}
+		if o.Iterations == 0 || o.Memory == 0 || o.Parallelism == 0 {
+			return fmt.Errorf("argon2 iterations, memory and parallelism must be positive")
+		}
+	case KdfAES:
+		if o.Iterations == 0 {
+			return fmt.Errorf("AES-KDF rounds must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported kdf: '%s', use '%s', '%s' or '%s'", o.Kdf, KdfArgon2d, KdfArgon2id, KdfAES)
+	}
+
+	if o.RootGroupName == "" {
+		return fmt.Errorf("root group name cannot be empty")
+	}
+
+	return nil
+}
+
+// NewDatabase - creates empty database with single root group
+func NewDatabase(credentials *gokeepasslib.DBCredentials, opts DatabaseOptions) (*gokeepasslib.Database, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var version = gokeepasslib.WithDatabaseKDBXVersion4()
+	if opts.Kdbx3 {
+		version = gokeepasslib.WithDatabaseKDBXVersion3()
+	}
+
+	db := gokeepasslib.NewDatabase(version)
+	db.Credentials = credentials
+
+	var headers = db.Header.FileHeaders
+	switch {
+	case opts.Kdbx3:
+		headers.TransformRounds = opts.Iterations
+	case opts.Kdf == KdfAES:
+		var seed [32]byte
+		_, err = rand.Read(seed[:])
+		if err != nil {
+			return nil, err
+		}
+
+		headers.KdfParameters = &gokeepasslib.KdfParameters{
+			UUID:   gokeepasslib.KdfAES4,
+			Rounds: opts.Iterations,
+			Salt:   seed,
+		}
+	default:
+		headers.KdfParameters.Iterations = opts.Iterations
+		headers.KdfParameters.Memory = opts.Memory * 1024 * 1024
+		headers.KdfParameters.Parallelism = opts.Parallelism
+		if opts.Kdf == KdfArgon2id {
+			headers.KdfParameters.UUID = kdfArgon2idUUID
+		}
+	}
+
+	var root = gokeepasslib.NewGroup()
+	root.Name = opts.RootGroupName
+	root.IsExpanded = wrappers.NewBoolWrapper(true)
+
+	db.Content.Meta.Generator = "enpass2gopass"
+	db.Content.Meta.DatabaseName = opts.RootGroupName
+	db.Content.Meta.RecycleBinEnabled = wrappers.NewBoolWrapper(true)
+	db.Content.Root = &gokeepasslib.RootData{
+		Groups: []gokeepasslib.Group{root},
+	}
+
+	return db, nil
+}
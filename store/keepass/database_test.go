@@ -0,0 +1,114 @@
+package keepass
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"golang.org/x/crypto/argon2"
+)
+
+func TestDatabaseOptionsKdf(t *testing.T) {
+	var tests = []struct {
+		kdf   string
+		kdbx3 bool
+		err   string
+	}{
+		{kdf: KdfArgon2d},
+		{kdf: KdfAES},
+		{kdf: KdfAES, kdbx3: true},
+		{kdf: KdfArgon2d, kdbx3: true, err: "not supported by KDBX 3.1"},
+		{kdf: KdfArgon2id},
+		{kdf: KdfArgon2id, kdbx3: true, err: "not supported by KDBX 3.1"},
+		{kdf: "scrypt", err: "unsupported kdf"},
+	}
+
+	for _, tt := range tests {
+		var opts = NewDatabaseOptions()
+		opts.Kdf, opts.Kdbx3 = tt.kdf, tt.kdbx3
+
+		err := opts.validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("kdf %s: unexpected error: %s", tt.kdf, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("kdf %s: expected error containing %q, got %v", tt.kdf, tt.err, err)
+		}
+	}
+}
+
+func TestArgon2idDatabase(t *testing.T) {
+	var dbPath = filepath.Join(t.TempDir(), "test.kdbx")
+	var credentials = gokeepasslib.NewPasswordCredentials("secret")
+	var opts = NewDatabaseOptions()
+	opts.Kdf, opts.Iterations, opts.Memory, opts.Parallelism = KdfArgon2id, 1, 1, 1
+
+	for _, password := range []string{"first", "second"} {
+		st, err := NewStore(dbPath, credentials, opts, "enpass", false, testLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = st.Save(itemFields("id-1", password, []byte("content")), "item"); err != nil {
+			t.Fatal(err)
+		}
+		if err = st.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	raw, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var params = kdfParameters(raw)
+	if !isArgon2id(params) || params.Iterations != 1 || params.Memory != 1024*1024 || params.Parallelism != 1 {
+		t.Fatalf("unexpected kdf parameters: %+v", params)
+	}
+
+	// header is authenticated with key derived by argon2id from composite key
+	var composite = sha256.Sum256(credentials.Passphrase)
+	var key = argon2.IDKey(composite[:], params.Salt[:], 1, 1024, 1, 32)
+	header, fields, err := readKdbx4Header(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(headerHMAC(header, fields[4], key), raw[len(header)+32:len(header)+64]) {
+		t.Error("header HMAC does not match argon2id key")
+	}
+
+	s, err := NewKeepassSource(dbPath, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.LoadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected one item, got %d", len(items))
+	}
+	entry := items[0].(KeepassItem).entry
+	if v := entry.GetPassword(); v != "second" {
+		t.Errorf("password = %q, expected second", v)
+	}
+	if len(entry.Binaries) != 1 {
+		t.Errorf("attachment is lost, binaries: %v", entry.Binaries)
+	}
+
+	_, _, err = openDatabase(dbPath, gokeepasslib.NewPasswordCredentials("wrong"))
+	if err == nil || !strings.Contains(err.Error(), "wrong credentials") {
+		t.Errorf("expected wrong credentials error, got %v", err)
+	}
+
+	// damaged files are reported, not panicked on
+	var damaged = &argon2idKey{argon2id: key, library: key}
+	for _, size := range []int{len(header) - 1, len(header) + 64, len(raw) - 1} {
+		if _, err = damaged.decode(raw[:size]); err == nil {
+			t.Errorf("database truncated to %d bytes is decoded", size)
+		}
+	}
+}
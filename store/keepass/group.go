@@ -0,0 +1,146 @@
+package keepass
+
+import (
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+const (
+	recycleBinName   = "Recycle Bin"
+	recycleBinIconID = 43
+)
+
+// rootGroup - returns database root group, keepass clients expect exactly one
+func (st *Store) rootGroup() *gokeepasslib.Group {
+	var root = st.db.Content.Root
+	if len(root.Groups) == 0 {
+		var g = gokeepasslib.NewGroup()
+		g.Name = "Root"
+		root.Groups = append(root.Groups, g)
+	}
+	return &root.Groups[0]
+}
+
+// getGroup - returns group by path, missing groups will be created
+func (st *Store) getGroup(group *gokeepasslib.Group, path []string) *gokeepasslib.Group {
+	if len(path) == 0 {
+		return group
+	}
+
+	if g := findChildGroup(group, path[0]); g != nil {
+		return st.getGroup(g, path[1:])
+	}
+
+	var sg = gokeepasslib.NewGroup()
+	sg.Name = path[0]
+	group.Groups = append(group.Groups, sg)
+	return st.getGroup(&group.Groups[len(group.Groups)-1], path[1:])
+}
+
+// findGroup - returns group by path or nil if it does not exist
+func findGroup(group *gokeepasslib.Group, path []string) *gokeepasslib.Group {
+	for _, name := range path {
+		group = findChildGroup(group, name)
+		if group == nil {
+			return nil
+		}
+	}
+	return group
+}
+
+func findChildGroup(group *gokeepasslib.Group, name string) *gokeepasslib.Group {
+	for i := range group.Groups {
+		if group.Groups[i].Name == name {
+			return &group.Groups[i]
+		}
+	}
+	return nil
+}
+
+// findGroupByUUID - searches group subtree for group with given uuid
+func findGroupByUUID(group *gokeepasslib.Group, id gokeepasslib.UUID) *gokeepasslib.Group {
+	if group.UUID.Compare(id) {
+		return group
+	}
+
+	for i := range group.Groups {
+		if g := findGroupByUUID(&group.Groups[i], id); g != nil {
+			return g
+		}
+	}
+	return nil
+}
+
+// findEntry - searches group subtree for entry; returned pointer is valid until tree is changed
+func findEntry(group *gokeepasslib.Group, skip *gokeepasslib.Group, match func(e *gokeepasslib.Entry) bool) (*gokeepasslib.Group, int) {
+	if group == skip {
+		return nil, -1
+	}
+
+	for i := range group.Entries {
+		if match(&group.Entries[i]) {
+			return group, i
+		}
+	}
+
+	for i := range group.Groups {
+		if g, idx := findEntry(&group.Groups[i], skip, match); g != nil {
+			return g, idx
+		}
+	}
+	return nil, -1
+}
+
+// walkEntries - calls fn for every entry of group subtree
+func walkEntries(group *gokeepasslib.Group, skip *gokeepasslib.Group, fn func(e *gokeepasslib.Entry)) {
+	if group == skip {
+		return
+	}
+
+	for i := range group.Entries {
+		fn(&group.Entries[i])
+	}
+
+	for i := range group.Groups {
+		walkEntries(&group.Groups[i], skip, fn)
+	}
+}
+
+// removeEntry - detaches entry from group
+func removeEntry(group *gokeepasslib.Group, idx int) gokeepasslib.Entry {
+	var e = group.Entries[idx]
+	group.Entries = append(group.Entries[:idx], group.Entries[idx+1:]...)
+	return e
+}
+
+// findRecycleBin - returns recycle bin group or nil if database has none
+func (st *Store) findRecycleBin() *gokeepasslib.Group {
+	var meta = st.db.Content.Meta
+	var empty gokeepasslib.UUID
+	if meta.RecycleBinUUID.Compare(empty) {
+		return nil
+	}
+	return findGroupByUUID(st.rootGroup(), meta.RecycleBinUUID)
+}
+
+// recycleBin - returns recycle bin group, it will be created like KeePassXC does
+func (st *Store) recycleBin() *gokeepasslib.Group {
+	if g := st.findRecycleBin(); g != nil {
+		return g
+	}
+
+	var root = st.rootGroup()
+	var g = gokeepasslib.NewGroup()
+	g.Name = recycleBinName
+	g.IconID = recycleBinIconID
+	g.EnableAutoType = wrappers.NewNullableBoolWrapper(false)
+	g.EnableSearching = wrappers.NewNullableBoolWrapper(false)
+	root.Groups = append(root.Groups, g)
+
+	var now = wrappers.Now()
+	var meta = st.db.Content.Meta
+	meta.RecycleBinEnabled = wrappers.NewBoolWrapper(true)
+	meta.RecycleBinUUID = g.UUID
+	meta.RecycleBinChanged = &now
+	return &root.Groups[len(root.Groups)-1]
+}
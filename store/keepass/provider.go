@@ -0,0 +1,123 @@
+package keepass
+
+import (
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+const (
+	// SourceName -
+	SourceName = "keepassSource"
+	// DestinationName -
+	DestinationName = "keepassDestination"
+)
+
+// credentialsOptions - options to unlock keepass database of source or destination
+func credentialsOptions(kind, passwordEnv string) []store.Option {
+	return []store.Option{
+		store.StringOption("password-env", passwordEnv, "environment variable with "+kind+" keepass database password"),
+		store.StringOption("password-file", "", "file with "+kind+" keepass database password"),
+		store.BoolOption("password-prompt", false, "prompt "+kind+" keepass database password"),
+		store.StringOption("key-file", "", kind+" keepass database key file"),
+	}
+}
+
+// credentials - builds keepass credentials from provider options
+func credentials(opts store.Options) (*gokeepasslib.DBCredentials, error) {
+	var keyFile = opts.String("key-file")
+
+	// ask password interactively if there is no other way to unlock database
+	password, ok, err := utils.ReadPassword(opts.String("password-env"), opts.String("password-file"),
+		opts.Bool("password-prompt") || keyFile == "", "KeePass password: ")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCredentials(password, ok, keyFile)
+}
+
+// databaseOptions - reads parameters of new keepass database from provider options
+func databaseOptions(opts store.Options) DatabaseOptions {
+	var out = NewDatabaseOptions()
+	if v := opts.String("root-group"); v != "" {
+		out.RootGroupName = v
+	}
+	if v := opts.String("kdf"); v != "" {
+		out.Kdf = v
+	}
+	if v := opts.Uint64("kdf-iterations"); v > 0 {
+		out.Iterations = v
+	}
+	if v := opts.Uint64("kdf-memory"); v > 0 {
+		out.Memory = v
+	}
+	if v := opts.Uint32("kdf-parallelism"); v > 0 {
+		out.Parallelism = v
+	}
+	out.Kdbx3 = opts.Bool("kdbx3")
+
+	// KDBX 3.1 supports AES-KDF only, default rounds are too weak for it
+	if out.Kdbx3 && !opts.Changed("kdf") {
+		out.Kdf = KdfAES
+	}
+	if out.Kdf == KdfAES && !opts.Changed("kdf-iterations") {
+		out.Iterations = 600000
+	}
+	return out
+}
+
+func init() {
+	store.RegisterSource(store.SourceProvider{
+		Provider: store.Provider{
+			Name:        SourceName,
+			Description: "KeePass KDBX 3.1 or 4 database",
+			FlagPrefix:  "source-keepass",
+			Options: append([]store.Option{
+				store.StringOption("path", "", "source keepass database path").Require(),
+			}, credentialsOptions("source", "KEEPASS_SOURCE_PASSWORD")...),
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreSource, error) {
+			creds, err := credentials(opts)
+			if err != nil {
+				return nil, err
+			}
+
+			s, err := NewKeepassSource(opts.String("path"), creds)
+			if err != nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	})
+
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        DestinationName,
+			Description: "KeePass KDBX database, it is created if it does not exist",
+			FlagPrefix:  "destination-keepass",
+			Options: append(append([]store.Option{
+				store.StringOption("path", "", "destination keepass database path").Require(),
+			}, credentialsOptions("destination", "KEEPASS_PASSWORD")...),
+				store.StringOption("root-group", "Root", "root group name of new destination keepass database"),
+				store.StringOption("kdf", KdfArgon2d, "key derivation function of new destination keepass database: argon2d, argon2id or aes"),
+				store.Uint64Option("kdf-iterations", 10, "argon2 iterations or AES-KDF rounds of new destination keepass database"),
+				store.Uint64Option("kdf-memory", 64, "argon2 memory in MiB of new destination keepass database"),
+				store.Uint32Option("kdf-parallelism", 2, "argon2 parallelism of new destination keepass database"),
+				store.BoolOption("kdbx3", false, "create new destination keepass database in KDBX 3.1 format"),
+			),
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			creds, err := credentials(opts)
+			if err != nil {
+				return nil, err
+			}
+
+			st, err := NewStore(opts.String("path"), creds, databaseOptions(opts), env.Prefix, env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
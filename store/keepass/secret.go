@@ -10,7 +10,13 @@ type Secret struct {
 	gokeepasslib.Entry
 }
 
-func (s Secret) setKey(k, v string, sensitivity bool) {
+func (s *Secret) setKey(k, v string, sensitivity bool) {
+	if idx := s.GetIndex(k); idx >= 0 {
+		s.Values[idx].Value.Content = v
+		s.Values[idx].Value.Protected = wrappers.NewBoolWrapper(sensitivity)
+		return
+	}
+
 	s.Values = append(s.Values, gokeepasslib.ValueData{
 		Key: k,
 		Value: gokeepasslib.V{
@@ -20,7 +26,7 @@ func (s Secret) setKey(k, v string, sensitivity bool) {
 	})
 }
 
-func (s Secret) setKeyOrAlt(k, altK, v string, sensitivity bool) {
+func (s *Secret) setKeyOrAlt(k, altK, v string, sensitivity bool) {
 	if t := s.GetContent(k); t == "" {
 		s.setKey(k, v, sensitivity)
 		return
@@ -33,3 +39,50 @@ func NewSecret() *Secret {
 	var sec = gokeepasslib.NewEntry()
 	return &Secret{sec}
 }
+
+// equal - compares secret content with existing entry
+func (s *Secret) equal(e *gokeepasslib.Entry) bool {
+	if s.Tags != e.Tags || len(s.Values) != len(e.Values) || len(s.Binaries) != len(e.Binaries) {
+		return false
+	}
+
+	for _, v := range s.Values {
+		ev := e.Get(v.Key)
+		if ev == nil || ev.Value.Content != v.Value.Content || ev.Value.Protected.Bool != v.Value.Protected.Bool {
+			return false
+		}
+	}
+
+	for i, b := range s.Binaries {
+		if e.Binaries[i].Name != b.Name || e.Binaries[i].Value.ID != b.Value.ID {
+			return false
+		}
+	}
+
+	return true
+}
+
+// update - applies secret content to existing entry keeping previous version in history
+func (s *Secret) update(e gokeepasslib.Entry, maxHistory int64) gokeepasslib.Entry {
+	var prev = e
+	prev.Histories = nil
+	prev.Values = append([]gokeepasslib.ValueData(nil), e.Values...)
+	prev.Binaries = append([]gokeepasslib.BinaryReference(nil), e.Binaries...)
+
+	if len(e.Histories) == 0 {
+		e.Histories = append(e.Histories, gokeepasslib.History{})
+	}
+
+	var history = &e.Histories[0]
+	history.Entries = append(history.Entries, prev)
+	if maxHistory >= 0 && int64(len(history.Entries)) > maxHistory {
+		history.Entries = history.Entries[int64(len(history.Entries))-maxHistory:]
+	}
+
+	var now = wrappers.Now()
+	e.Values = s.Values
+	e.Tags = s.Tags
+	e.Binaries = s.Binaries
+	e.Times.LastModificationTime = &now
+	return e
+}
@@ -0,0 +1,186 @@
+package keepass
+
+import (
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// standard keepass entry string keys
+var standardKeys = []string{"Title", "UserName", "Password", "URL", "Notes"}
+
+// otpKey - attribute with otpauth uri used by keepassxc
+const otpKey = "otp"
+
+// KeepassSource -
+type KeepassSource struct {
+	path        string
+	credentials *gokeepasslib.DBCredentials
+}
+
+// KeepassItem -
+type KeepassItem struct {
+	groups   []string
+	entry    gokeepasslib.Entry
+	binaries map[int][]byte
+}
+
+// GetSecretPath -
+func (i KeepassItem) GetSecretPath() (out string, err error) {
+	for _, g := range i.groups {
+		if g = utils.Transliterate(g); g != "" {
+			out = filepath.Join(out, g)
+		}
+	}
+
+	var title = utils.Transliterate(i.entry.GetTitle())
+	if title == "" {
+		return "", errors.New("title cannot be empty")
+	}
+
+	return filepath.Join(out, title), nil
+}
+
+// GetFields -
+func (i KeepassItem) GetFields() (out []field.FieldInterface, err error) {
+	var e = i.entry
+	if v := e.GetTitle(); v != "" {
+		out = append(out, field.NewTitleField("", v))
+	}
+
+	out = append(out, field.NewIDField("keepass_uuid", hex.EncodeToString(e.UUID[:])))
+
+	if v := e.GetContent("UserName"); v != "" {
+		out = append(out, field.NewUsernameField("", v))
+	}
+
+	if v := e.GetPassword(); v != "" {
+		out = append(out, field.NewPasswordField("", v))
+	}
+
+	if v := e.GetContent("URL"); v != "" {
+		out = append(out, field.NewUrlField("", v))
+	}
+
+	if v := e.Tags; v != "" {
+		out = append(out, field.NewTagsField("", v))
+	}
+
+	if v := e.GetContent("Notes"); v != "" {
+		out = append(out, field.NewSimpleField("notes", []byte(v), true, false))
+	}
+
+	for _, v := range e.Values {
+		if utils.InList(standardKeys, v.Key) || v.Value.Content == "" {
+			continue
+		}
+
+		if v.Key == otpKey {
+			var issuer = utils.OTPIssuer(e.GetTitle(), e.GetContent("URL"))
+			if uri, ok := utils.OTPAuthURI(v.Value.Content, issuer, e.GetContent("UserName")); ok {
+				out = append(out, field.NewOTPField("", uri))
+				continue
+			}
+		}
+
+		var label = utils.Transliterate(v.Key)
+		if label == "" {
+			continue
+		}
+
+		var multiline = strings.Contains(v.Value.Content, "\n")
+		out = append(out, field.NewSimpleField(label, []byte(v.Value.Content), multiline, v.Value.Protected.Bool))
+	}
+
+	for _, ref := range e.Binaries {
+		data, ok := i.binaries[ref.Value.ID]
+		if !ok {
+			return nil, errors.New("binary not found: " + ref.Name)
+		}
+		out = append(out, field.NewAttachmentField(ref.Name, data))
+	}
+
+	return
+}
+
+// collectItems - walks group tree and returns its entries with groups path
+func collectItems(group *gokeepasslib.Group, path []string, skip *gokeepasslib.Group, binaries map[int][]byte) (out []store.StoreSourceItem) {
+	if group == skip {
+		return nil
+	}
+
+	for _, e := range group.Entries {
+		out = append(out, KeepassItem{
+			groups:   path,
+			entry:    e,
+			binaries: binaries,
+		})
+	}
+
+	for i := range group.Groups {
+		var sub = &group.Groups[i]
+		var subPath = append(append([]string(nil), path...), sub.Name)
+		out = append(out, collectItems(sub, subPath, skip, binaries)...)
+	}
+
+	return
+}
+
+// LoadData -
+func (s KeepassSource) LoadData() (o []store.StoreSourceItem, err error) {
+	db, _, err := openDatabase(s.path, s.credentials)
+	if err != nil {
+		return
+	}
+
+	var binaries = make(map[int][]byte)
+	var dbBinaries = db.Content.Meta.Binaries
+	if db.Header.IsKdbx4() {
+		dbBinaries = db.Content.InnerHeader.Binaries
+	}
+
+	for _, b := range dbBinaries {
+		binaries[b.ID], err = b.GetContentBytes()
+		if err != nil {
+			return
+		}
+	}
+
+	var recycleBin *gokeepasslib.Group
+	var empty gokeepasslib.UUID
+	for i := range db.Content.Root.Groups {
+		var root = &db.Content.Root.Groups[i]
+		if !db.Content.Meta.RecycleBinUUID.Compare(empty) {
+			recycleBin = findGroupByUUID(root, db.Content.Meta.RecycleBinUUID)
+		}
+
+		// root group name is not a part of secret path
+		o = append(o, collectItems(root, nil, recycleBin, binaries)...)
+	}
+
+	return o, nil
+}
+
+// GetSourcePath -
+func (s KeepassSource) GetSourcePath() string {
+	return s.path
+}
+
+// NewKeepassSource -
+func NewKeepassSource(dbPath string, credentials *gokeepasslib.DBCredentials) (o *KeepassSource, err error) {
+	absPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return
+	}
+
+	return &KeepassSource{
+		path:        absPath,
+		credentials: credentials,
+	}, nil
+}
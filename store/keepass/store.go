@@ -1,6 +1,7 @@
 package keepass
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,60 +11,264 @@ import (
 	"github.com/revengel/enpass2gopass/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
 )
 
 // Store -
 type Store struct {
-	db     *gokeepasslib.Database
-	prefix string
-	items  *utils.UniqueStrings
-	dryrun bool
-	logger *logrus.Logger
-	file   *os.File
+	db       *gokeepasslib.Database
+	kdf      *argon2idKey
+	path     string
+	prefix   string
+	items    *utils.UniqueStrings
+	binaries map[string]int
+	seen     map[gokeepasslib.UUID]bool
+	dryrun   bool
+	changed  bool
+	logger   *logrus.Logger
 }
 
-// Close -
+// Close - writes database to disk if anything has been changed
 func (st *Store) Close() error {
+	if st.dryrun || !st.changed {
+		return nil
+	}
+
+	err := st.write()
+	if err != nil {
+		return fmt.Errorf("cannot write keepass database '%s': %s", st.path, err.Error())
+	}
+
+	// encoder drops binaries referenced by neither entries nor their history
+	// and renumbers the rest, so replaced attachments do not stay orphaned
+	st.changed = false
+	st.indexBinaries()
+	st.logger.WithField("path", st.path).Info("keepass database has been written")
+	return nil
+}
+
+// Discard - drops decrypted database without writing it
+func (st *Store) Discard() error {
+	st.db = nil
+	st.changed = false
 	return nil
 }
 
-// Cleanup -
+// write - encodes database into temporary file and atomically replaces the target
+func (st *Store) write() (err error) {
+	var mode os.FileMode = 0600
+	if fi, err := os.Stat(st.path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(st.path), "."+filepath.Base(st.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+
+	var tmpPath = tmp.Name()
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	// encoder expects protected values to be locked,
+	// they are unlocked again on every path to keep store usable
+	err = st.db.LockProtectedEntries()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if unlockErr := st.db.UnlockProtectedEntries(); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	var buf bytes.Buffer
+	err = gokeepasslib.NewEncoder(&buf).Encode(st.db)
+	if err != nil {
+		return
+	}
+
+	var raw = buf.Bytes()
+	if st.kdf != nil {
+		raw, err = st.kdf.encode(raw)
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = tmp.Write(raw)
+	if err != nil {
+		return
+	}
+
+	err = tmp.Chmod(mode)
+	if err != nil {
+		return
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		return
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return
+	}
+
+	return os.Rename(tmpPath, st.path)
+}
+
+// indexBinaries - maps content hashes of database binaries to their ids
+func (st *Store) indexBinaries() {
+	var binaries = st.db.Content.Meta.Binaries
+	if st.db.Header.IsKdbx4() {
+		binaries = st.db.Content.InnerHeader.Binaries
+	}
+
+	st.binaries = make(map[string]int)
+	for _, b := range binaries {
+		data, err := b.GetContentBytes()
+		if err != nil {
+			st.logger.Warnf("cannot read keepass binary %d: %s", b.ID, err.Error())
+			continue
+		}
+		st.binaries[utils.GetHashFromBytes(data)] = b.ID
+	}
+}
+
+// addBinary - adds binary to database, byte-identical content is stored once
+func (st *Store) addBinary(data []byte) int {
+	var hash = utils.GetHashFromBytes(data)
+	if id, ok := st.binaries[hash]; ok {
+		return id
+	}
+
+	var b = st.db.AddBinary(data)
+	st.binaries[hash] = b.ID
+	return b.ID
+}
+
+// attachBinary - references binary from entry, names must be unique within entry
+func (st *Store) attachBinary(s *Secret, name string, data []byte) {
+	var refName = name
+	for i := 2; ; i++ {
+		var exists bool
+		for _, ref := range s.Binaries {
+			if ref.Name == refName {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			break
+		}
+
+		var ext = filepath.Ext(name)
+		refName = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+
+	s.Binaries = append(s.Binaries, gokeepasslib.NewBinaryReference(refName, st.addBinary(data)))
+}
+
+// Cleanup - moves entries under prefix which were not saved during this run to recycle bin
 func (st *Store) Cleanup() (bool, error) {
-	return false, nil
+	var prefixGroup = findGroup(st.rootGroup(), st.prefixPath())
+	if prefixGroup == nil {
+		return false, nil
+	}
+
+	var stale []gokeepasslib.UUID
+	walkEntries(prefixGroup, st.findRecycleBin(), func(e *gokeepasslib.Entry) {
+		if st.seen[e.UUID] {
+			return
+		}
+
+		st.logger.WithField("type", "cleaner").
+			WithField("keepasskey", e.GetTitle()).
+			Info("keepass entry will be moved to recycle bin")
+		stale = append(stale, e.UUID)
+	})
+
+	if st.dryrun || len(stale) == 0 {
+		return false, nil
+	}
+
+	for _, id := range stale {
+		group, idx := findEntry(findGroup(st.rootGroup(), st.prefixPath()), st.findRecycleBin(), func(e *gokeepasslib.Entry) bool {
+			return e.UUID.Compare(id)
+		})
+		if group == nil {
+			continue
+		}
+
+		var e = removeEntry(group, idx)
+		var now = wrappers.Now()
+		e.Times.LocationChanged = &now
+		var bin = st.recycleBin()
+		bin.Entries = append(bin.Entries, e)
+	}
+
+	st.changed = true
+	return true, nil
+}
+
+// prefixPath - returns groups path of store prefix
+func (st *Store) prefixPath() []string {
+	groups, name := st.splitPath("")
+	if name == "" {
+		return nil
+	}
+	return append(groups, name)
 }
 
-func (st *Store) createGroupRecursive(group *gokeepasslib.Group, path []string) *gokeepasslib.Group {
-	for _, g := range group.Groups {
-		if g.Name == path[0] {
-			return st.createGroupRecursive(&g, path[1:])
+// splitPath - splits secret path into groups path and entry name
+func (st *Store) splitPath(p string) (groups []string, name string) {
+	var secretPath = filepath.ToSlash(filepath.Join(st.prefix, p))
+	for _, level := range strings.Split(secretPath, "/") {
+		if level == "" {
+			continue
 		}
+		groups = append(groups, level)
 	}
 
-	var sg = gokeepasslib.NewGroup()
-	sg.Name = path[0]
-	group.Groups = append(group.Groups, sg)
-	return st.createGroupRecursive(&sg, path[1:])
+	if len(groups) == 0 {
+		return nil, ""
+	}
+
+	return groups[:len(groups)-1], groups[len(groups)-1]
 }
 
 // Save -
 func (st *Store) Save(fields []field.FieldInterface, p string) (bool, error) {
-	var err error
 	var mainSecret = NewSecret()
 	var attachments []field.FieldInterface
+	var idKey, idValue string
 	for _, f := range fields {
 		switch f.GetType() {
 		case field.SecretTitleField:
 			mainSecret.setKeyOrAlt("Title", f.GetKey(), f.GetValueString(), false)
 		case field.SecretUsernameField:
-			mainSecret.setKeyOrAlt("Username", f.GetKey(), f.GetValueString(), false)
+			mainSecret.setKeyOrAlt("UserName", f.GetKey(), f.GetValueString(), false)
 		case field.SecretPasswordField:
 			mainSecret.setKeyOrAlt("Password", f.GetKey(), f.GetValueString(), true)
 		case field.SecretURLField:
 			mainSecret.setKeyOrAlt("URL", f.GetKey(), f.GetValueString(), false)
 		case field.SecretTagsField:
-			mainSecret.setKeyOrAlt("Tags", f.GetKey(), f.GetValueString(), false)
+			mainSecret.Tags = f.GetValueString()
+		case field.SecretIDField:
+			idKey, idValue = f.GetKey(), f.GetValueString()
+			mainSecret.setKey(idKey, idValue, false)
+		case field.SecretOTPField:
+			// keepassxc reads otpauth uri from otp attribute
+			mainSecret.setKeyOrAlt(otpKey, f.GetKey(), f.GetValueString(), true)
 		case field.SecretAttachmentField:
-			_ = append(attachments, f)
+			attachments = append(attachments, f)
 		default:
 			if f.IsMultiline() {
 				var notes = mainSecret.GetContent("Notes")
@@ -75,71 +280,170 @@ func (st *Store) Save(fields []field.FieldInterface, p string) (bool, error) {
 		}
 	}
 
-	var secretPath = filepath.Join(st.prefix, p)
-	// Split the group path into individual levels
-	groupLevels := strings.Split(secretPath, "/")
+	p = st.items.Unique(p)
+	groupPath, name := st.splitPath(p)
+	if name == "" {
+		return false, fmt.Errorf("invalid secret path: '%s'", p)
+	}
 
-	// Start from the root group
-	var rootGroup *gokeepasslib.Group
-	for _, gr := range st.db.Content.Root.Groups {
-		if gr.Name == groupLevels[0] {
-			rootGroup = &gr
-			break
+	if mainSecret.GetContent("Title") == "" {
+		mainSecret.setKey("Title", name, false)
+	}
+
+	// binaries are never written in dry run, but are needed to compare entries
+	for _, f := range attachments {
+		st.attachBinary(mainSecret, f.GetKey(), f.GetValue())
+	}
+
+	var l = st.logger.WithField("keepasskey", p)
+	var root = st.rootGroup()
+	var title = mainSecret.GetTitle()
+	var group *gokeepasslib.Group
+	var idx = -1
+	// entries outside of prefix are not managed by this store even if they have the same id
+	if prefixGroup := findGroup(root, st.prefixPath()); prefixGroup != nil && idValue != "" {
+		group, idx = findEntry(prefixGroup, st.findRecycleBin(), func(e *gokeepasslib.Entry) bool {
+			return !st.seen[e.UUID] && e.GetContent(idKey) == idValue
+		})
+	}
+
+	// items without stable identity are matched by location and title
+	if group == nil && idValue == "" {
+		if g := findGroup(root, groupPath); g != nil {
+			for i := range g.Entries {
+				if !st.seen[g.Entries[i].UUID] && g.Entries[i].GetTitle() == title {
+					group, idx = g, i
+					break
+				}
+			}
 		}
 	}
 
-	if rootGroup == nil {
-		gr := gokeepasslib.NewGroup()
-		gr.Name = groupLevels[0]
-		rootGroup = &gr
-		st.db.Content.Root.Groups = append(st.db.Content.Root.Groups, *rootGroup)
+	if group == nil {
+		st.seen[mainSecret.UUID] = true
+		l.Info("secret will be created")
+		if st.dryrun {
+			return true, nil
+		}
+
+		var target = st.getGroup(root, groupPath)
+		target.Entries = append(target.Entries, mainSecret.Entry)
+		st.changed = true
+		return true, nil
 	}
 
-	// Create the groups with the specified path recursively
-	lastGroup := st.createGroupRecursive(rootGroup, groupLevels[1:])
-	lastGroup.Entries = append(lastGroup.Entries, mainSecret.Entry)
+	var existing = group.Entries[idx]
+	st.seen[existing.UUID] = true
+	var sameGroup = group == findGroup(root, groupPath)
+	if sameGroup && mainSecret.equal(&existing) {
+		l.Debug("keepass entry already in actual state")
+		return false, nil
+	}
 
-	defer func() {
-		err = st.db.LockProtectedEntries()
-	}()
+	l.Info("secret will be updated")
+	if st.dryrun {
+		return true, nil
+	}
 
-	encoder := gokeepasslib.NewEncoder(st.file)
-	err = encoder.Encode(st.db)
-	if err != nil {
-		return false, err
+	var updated = existing
+	if !mainSecret.equal(&existing) {
+		updated = mainSecret.update(existing, st.db.Content.Meta.HistoryMaxItems)
+	}
+
+	if sameGroup {
+		group.Entries[idx] = updated
+	} else {
+		removeEntry(group, idx)
+		var now = wrappers.Now()
+		updated.Times.LocationChanged = &now
+		var target = st.getGroup(st.rootGroup(), groupPath)
+		target.Entries = append(target.Entries, updated)
 	}
 
-	return false, nil
+	st.changed = true
+	l.Info("secret has been updated")
+	return true, nil
 }
 
-// NewStore -
-func NewStore(dbPath, password, prefix string, dryrun bool, logger *logrus.Logger) (store *Store, err error) {
-	absDbPath, err := filepath.Abs(dbPath)
+// openDatabase - decodes existing database and unlocks protected values,
+// argon2id database is returned along with its keys
+func openDatabase(p string, credentials *gokeepasslib.DBCredentials) (*gokeepasslib.Database, *argon2idKey, error) {
+	raw, err := os.ReadFile(p)
 	if err != nil {
-		return
+		return nil, nil, err
 	}
 
-	file, err := os.Open(absDbPath)
+	kdf, err := newArgon2idKey(credentials, kdfParameters(raw))
+	if err == nil && kdf != nil {
+		raw, err = kdf.decode(raw)
+	}
 	if err != nil {
-		return
+		return nil, nil, fmt.Errorf("cannot decode keepass database '%s': %s", p, err.Error())
 	}
 
 	db := gokeepasslib.NewDatabase()
-	db.Credentials = gokeepasslib.NewPasswordCredentials(password)
-	err = gokeepasslib.NewDecoder(file).Decode(db)
+	db.Credentials = credentials
+	err = gokeepasslib.NewDecoder(bytes.NewReader(raw)).Decode(db)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot decode keepass database '%s': %s", p, err.Error())
+	}
+
+	err = db.UnlockProtectedEntries()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, kdf, nil
+}
+
+// NewStore -
+func NewStore(dbPath string, credentials *gokeepasslib.DBCredentials, dbOpts DatabaseOptions, prefix string, dryrun bool, logger *logrus.Logger) (store *Store, err error) {
+	absDbPath, err := filepath.Abs(dbPath)
 	if err != nil {
 		return
 	}
 
+	var db *gokeepasslib.Database
+	var kdf *argon2idKey
+	var changed bool
+	_, err = os.Stat(absDbPath)
+	switch {
+	case os.IsNotExist(err):
+		logger.WithField("path", absDbPath).Info("keepass database does not exist and will be created")
+		db, err = NewDatabase(credentials, dbOpts)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create keepass database: %s", err.Error())
+		}
+		kdf, err = newArgon2idKey(credentials, db.Header.FileHeaders.KdfParameters)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create keepass database: %s", err.Error())
+		}
+		changed = true
+	case err != nil:
+		return
+	default:
+		db, kdf, err = openDatabase(absDbPath, credentials)
+		if err != nil {
+			return
+		}
+	}
+
 	if prefix == "" {
 		prefix = "enpass"
 	}
 
-	return &Store{
-		db:     db,
-		prefix: prefix,
-		items:  utils.NewUniqueStrings(logger),
-		dryrun: dryrun,
-		logger: logger,
-	}, nil
+	store = &Store{
+		db:      db,
+		kdf:     kdf,
+		seen:    make(map[gokeepasslib.UUID]bool),
+		path:    absDbPath,
+		prefix:  prefix,
+		items:   utils.NewUniqueStrings(logger),
+		dryrun:  dryrun,
+		changed: changed,
+		logger:  logger,
+	}
+
+	store.indexBinaries()
+	return store, nil
 }
@@ -0,0 +1,160 @@
+package keepass
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func testLogger() *logrus.Logger {
+	var l = logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+// testStore - opens store on database in temporary directory, AES-KDF keeps tests fast
+func testStore(t *testing.T, dbPath, prefix string) *Store {
+	t.Helper()
+	var opts = NewDatabaseOptions()
+	opts.Kdf, opts.Iterations = KdfAES, 1
+
+	st, err := NewStore(dbPath, gokeepasslib.NewPasswordCredentials("secret"), opts, prefix, false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func itemFields(id, password string, attachment []byte) []field.FieldInterface {
+	var out = []field.FieldInterface{
+		field.NewTitleField("", "item"),
+		field.NewIDField("enpass_uuid", id),
+		field.NewPasswordField("", password),
+	}
+	if attachment != nil {
+		out = append(out, field.NewAttachmentField("file.txt", attachment))
+	}
+	return out
+}
+
+func TestStoreMatchesIDWithinPrefix(t *testing.T) {
+	var dbPath = filepath.Join(t.TempDir(), "test.kdbx")
+
+	var other = testStore(t, dbPath, "other")
+	if _, err := other.Save(itemFields("id-1", "other", nil), "item"); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var st = testStore(t, dbPath, "import")
+	created, err := st.Save(itemFields("id-1", "imported", nil), "item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatal("entry with the same id outside of prefix has been reused")
+	}
+	if _, err = st.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var reopened = testStore(t, dbPath, "import")
+	for prefix, password := range map[string]string{"other": "other", "import": "imported"} {
+		g := findGroup(reopened.rootGroup(), []string{prefix})
+		if g == nil || len(g.Entries) != 1 {
+			t.Fatalf("group %s must contain one entry", prefix)
+		}
+		if v := g.Entries[0].GetPassword(); v != password {
+			t.Errorf("group %s: expected password %q, got %q", prefix, password, v)
+		}
+	}
+}
+
+func TestStoreDropsReplacedBinaries(t *testing.T) {
+	var dbPath = filepath.Join(t.TempDir(), "test.kdbx")
+
+	var st = testStore(t, dbPath, "import")
+	st.db.Content.Meta.HistoryMaxItems = 0
+	for _, data := range []string{"first", "second"} {
+		if _, err := st.Save(itemFields("id-1", "pass", []byte(data)), "item"); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Close(); err != nil {
+			t.Fatal(err)
+		}
+		st = testStore(t, dbPath, "import")
+	}
+
+	var binaries = st.db.Content.InnerHeader.Binaries
+	if len(binaries) != 1 {
+		t.Fatalf("expected one binary, got %d", len(binaries))
+	}
+
+	data, err := binaries[0].GetContentBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" {
+		t.Errorf("expected binary of updated attachment, got %q", data)
+	}
+
+	e := findGroup(st.rootGroup(), []string{"import"}).Entries[0]
+	if len(e.Binaries) != 1 || e.Binaries[0].Value.ID != binaries[0].ID {
+		t.Errorf("entry references %v, binary id is %d", e.Binaries, binaries[0].ID)
+	}
+}
+
+func TestStoreWriteFailureKeepsEntriesUnlocked(t *testing.T) {
+	var dbPath = filepath.Join(t.TempDir(), "test.kdbx")
+
+	var st = testStore(t, dbPath, "import")
+	if _, err := st.Save(itemFields("id-1", "secret", nil), "item"); err != nil {
+		t.Fatal(err)
+	}
+
+	// encoder cannot build master key without credentials
+	var credentials = st.db.Credentials
+	st.db.Credentials = nil
+	if err := st.Close(); err == nil {
+		t.Fatal("write without credentials must fail")
+	}
+
+	st.db.Credentials = credentials
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var reopened = testStore(t, dbPath, "import")
+	e := findGroup(reopened.rootGroup(), []string{"import"}).Entries[0]
+	if v := e.GetPassword(); v != "secret" {
+		t.Errorf("password after failed write = %q, expected %q", v, "secret")
+	}
+}
+
+func TestStoreDiscard(t *testing.T) {
+	var dbPath = filepath.Join(t.TempDir(), "test.kdbx")
+	var st = testStore(t, dbPath, "enpass")
+	if _, err := st.Save(itemFields("id-1", "secret", nil), "item"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.Discard(); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("discarded database has been written: %v", err)
+	}
+}
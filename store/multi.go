@@ -0,0 +1,154 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+)
+
+// namedDestination - destination of multi destination with its results
+type namedDestination struct {
+	name    string
+	dest    StoreDestination
+	saved   int
+	changed int
+	err     error
+}
+
+// MultiDestination - writes the same secrets to several destinations
+type MultiDestination struct {
+	destinations    []*namedDestination
+	continueOnError bool
+	logger          *logrus.Logger
+}
+
+// NewMultiDestination - with continueOnError failed destination is reported and skipped,
+// otherwise the first error aborts the run
+func NewMultiDestination(continueOnError bool, logger *logrus.Logger) *MultiDestination {
+	return &MultiDestination{
+		continueOnError: continueOnError,
+		logger:          logger,
+	}
+}
+
+// Add -
+func (m *MultiDestination) Add(name string, d StoreDestination) {
+	m.destinations = append(m.destinations, &namedDestination{name: name, dest: d})
+}
+
+// Len - count of destinations
+func (m *MultiDestination) Len() int {
+	return len(m.destinations)
+}
+
+// fail - marks destination as failed, returns error if run has to be aborted
+func (m *MultiDestination) fail(d *namedDestination, err error) error {
+	if !m.continueOnError {
+		return fmt.Errorf("destination %s: %s", d.name, err.Error())
+	}
+
+	// failed destination is not cleaned up, secrets which were not saved must stay there
+	d.err = err
+	m.logger.WithField("destination", d.name).Errorf("destination failed and will be skipped: %s", err.Error())
+
+	for _, d := range m.destinations {
+		if d.err == nil {
+			return nil
+		}
+	}
+	return errors.New("all destinations failed")
+}
+
+// Save -
+func (m *MultiDestination) Save(fields []field.FieldInterface, p string) (out bool, err error) {
+	for _, d := range m.destinations {
+		if d.err != nil {
+			continue
+		}
+
+		// every destination gets own slice, so appends of one cannot affect another
+		changed, err := d.dest.Save(append([]field.FieldInterface(nil), fields...), p)
+		if err != nil {
+			err = m.fail(d, err)
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		d.saved++
+		if changed {
+			d.changed++
+			out = true
+		}
+	}
+	return
+}
+
+// Cleanup -
+func (m *MultiDestination) Cleanup() (out bool, err error) {
+	for _, d := range m.destinations {
+		if d.err != nil {
+			continue
+		}
+
+		changed, err := d.dest.Cleanup()
+		if err != nil {
+			err = m.fail(d, err)
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+		out = out || changed
+	}
+	return
+}
+
+// Close - closes all destinations and reports their results
+func (m *MultiDestination) Close() error {
+	var firstErr error
+	for _, d := range m.destinations {
+		// failed destination is not closed, so its partial changes are not written or committed,
+		// only its resources are released
+		if discarder, ok := d.dest.(StoreDiscarder); ok && d.err != nil {
+			err := discarder.Discard()
+			if err != nil {
+				m.logger.WithField("destination", d.name).Warnf("cannot release failed destination: %s", err.Error())
+			}
+		} else if d.err == nil {
+			err := d.dest.Close()
+			if err != nil {
+				d.err = err
+				if firstErr == nil {
+					firstErr = fmt.Errorf("destination %s: %s", d.name, err.Error())
+				}
+			}
+		}
+
+		var l = m.logger.WithField("destination", d.name).
+			WithField("saved", d.saved).
+			WithField("changed", d.changed)
+		if d.err != nil {
+			l.Errorf("destination import failed: %s", d.err.Error())
+			continue
+		}
+		l.Info("destination import finished")
+	}
+
+	if firstErr != nil && !m.continueOnError {
+		return firstErr
+	}
+
+	for _, d := range m.destinations {
+		if d.err == nil {
+			return nil
+		}
+	}
+	if len(m.destinations) > 0 {
+		return errors.New("all destinations failed")
+	}
+	return nil
+}
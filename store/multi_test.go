@@ -0,0 +1,104 @@
+package store
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeDestination - records calls, fails operations which have error set
+type fakeDestination struct {
+	saveErr    error
+	cleanupErr error
+	saved      []string
+	cleaned    bool
+	closed     bool
+	discarded  bool
+}
+
+func (d *fakeDestination) Save(fields []field.FieldInterface, p string) (bool, error) {
+	if d.saveErr != nil {
+		return false, d.saveErr
+	}
+	d.saved = append(d.saved, p)
+	return true, nil
+}
+
+func (d *fakeDestination) Cleanup() (bool, error) {
+	if d.cleanupErr != nil {
+		return false, d.cleanupErr
+	}
+	d.cleaned = true
+	return false, nil
+}
+
+func (d *fakeDestination) Close() error {
+	d.closed = true
+	return nil
+}
+
+func (d *fakeDestination) Discard() error {
+	d.discarded = true
+	return nil
+}
+
+func TestMultiDestinationSkipsFailedDestinations(t *testing.T) {
+	var logger = logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var failedSave = &fakeDestination{saveErr: errors.New("save failed")}
+	var failedCleanup = &fakeDestination{cleanupErr: errors.New("cleanup failed")}
+	var ok = &fakeDestination{}
+
+	var m = NewMultiDestination(true, logger)
+	m.Add("failedSave", failedSave)
+	m.Add("failedCleanup", failedCleanup)
+	m.Add("ok", ok)
+
+	for _, p := range []string{"first", "second"} {
+		if _, err := m.Save(nil, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if failedSave.closed || failedCleanup.closed {
+		t.Error("failed destination has been closed")
+	}
+	if !failedSave.discarded || !failedCleanup.discarded {
+		t.Error("resources of failed destination have not been released")
+	}
+	if failedSave.cleaned {
+		t.Error("destination failed on save has been cleaned up")
+	}
+	if !ok.closed || ok.discarded || !ok.cleaned || len(ok.saved) != 2 {
+		t.Errorf("healthy destination: saved %v, cleaned %v, closed %v, discarded %v", ok.saved, ok.cleaned, ok.closed, ok.discarded)
+	}
+}
+
+func TestMultiDestinationAllFailed(t *testing.T) {
+	var logger = logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var d = &fakeDestination{saveErr: errors.New("save failed")}
+	var m = NewMultiDestination(true, logger)
+	m.Add("failed", d)
+
+	if _, err := m.Save(nil, "first"); err == nil {
+		t.Error("error is not returned when all destinations failed")
+	}
+	if err := m.Close(); err == nil {
+		t.Error("close does not report that all destinations failed")
+	}
+	if d.closed || !d.discarded {
+		t.Errorf("failed destination: closed %v, discarded %v", d.closed, d.discarded)
+	}
+}
@@ -0,0 +1,196 @@
+package onepassword
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// categories - 1password item category uuid to path name
+var categories = map[string]string{
+	"001": "login",
+	"002": "creditcard",
+	"003": "note",
+	"004": "identity",
+	"005": "password",
+	"006": "document",
+	"100": "software_license",
+	"101": "bank_account",
+	"102": "database",
+	"103": "driver_license",
+	"104": "outdoor_license",
+	"105": "membership",
+	"106": "passport",
+	"107": "rewards",
+	"108": "ssn",
+	"109": "wireless_router",
+	"110": "server",
+	"111": "email_account",
+	"112": "api_credential",
+	"113": "medical_record",
+	"114": "ssh_key",
+	"115": "crypto_wallet",
+}
+
+// Export - export.data content
+type Export struct {
+	Accounts []Account `json:"accounts"`
+}
+
+// Account -
+type Account struct {
+	Attrs struct {
+		AccountName string `json:"accountName"`
+		Name        string `json:"name"`
+		Email       string `json:"email"`
+		UUID        string `json:"uuid"`
+	} `json:"attrs"`
+	Vaults []Vault `json:"vaults"`
+}
+
+// Vault -
+type Vault struct {
+	Attrs struct {
+		UUID string `json:"uuid"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"attrs"`
+	Items []Item `json:"items"`
+}
+
+// Item -
+type Item struct {
+	UUID         string   `json:"uuid"`
+	FavIndex     int      `json:"favIndex"`
+	State        string   `json:"state"`
+	CategoryUUID string   `json:"categoryUuid"`
+	Details      Details  `json:"details"`
+	Overview     Overview `json:"overview"`
+}
+
+// Details -
+type Details struct {
+	LoginFields        []LoginField `json:"loginFields"`
+	NotesPlain         string       `json:"notesPlain"`
+	Sections           []Section    `json:"sections"`
+	Password           string       `json:"password"`
+	DocumentAttributes *FileValue   `json:"documentAttributes"`
+}
+
+// LoginField -
+type LoginField struct {
+	Value       string `json:"value"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	FieldType   string `json:"fieldType"`
+	Designation string `json:"designation"`
+}
+
+// Section -
+type Section struct {
+	Title  string         `json:"title"`
+	Name   string         `json:"name"`
+	Fields []SectionField `json:"fields"`
+}
+
+// SectionField -
+type SectionField struct {
+	Title     string                     `json:"title"`
+	ID        string                     `json:"id"`
+	Value     map[string]json.RawMessage `json:"value"`
+	Multiline bool                       `json:"multiline"`
+}
+
+// FileValue - reference to file stored in files/ directory of archive
+type FileValue struct {
+	FileName      string `json:"fileName"`
+	DocumentID    string `json:"documentId"`
+	DecryptedSize int64  `json:"decryptedSize"`
+}
+
+// Overview -
+type Overview struct {
+	Subtitle string `json:"subtitle"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Urls     []struct {
+		Label string `json:"label"`
+		URL   string `json:"url"`
+	} `json:"urls"`
+	Tags []string `json:"tags"`
+}
+
+// IsArchived -
+func (i Item) IsArchived() bool {
+	return i.State == "archived"
+}
+
+// IsFavorite -
+func (i Item) IsFavorite() bool {
+	return i.FavIndex > 0
+}
+
+// GetCategoryPath -
+func (i Item) GetCategoryPath() string {
+	if c, ok := categories[i.CategoryUUID]; ok {
+		return c
+	}
+	return "other"
+}
+
+// sectionValue - typed section field value
+type sectionValue struct {
+	value     string
+	sensitive bool
+	totp      bool
+	file      *FileValue
+}
+
+// GetValue - decodes section field value, unsupported types are returned as raw json
+func (f SectionField) GetValue() (out sectionValue, err error) {
+	for kind, raw := range f.Value {
+		switch kind {
+		case "concealed", "creditCardNumber":
+			out.sensitive = true
+			err = json.Unmarshal(raw, &out.value)
+		case "totp":
+			out.sensitive = true
+			out.totp = true
+			err = json.Unmarshal(raw, &out.value)
+		case "string", "url", "phone", "menu", "creditCardType", "gender":
+			err = json.Unmarshal(raw, &out.value)
+		case "date", "monthYear":
+			var n int64
+			err = json.Unmarshal(raw, &n)
+			if err == nil && n != 0 {
+				out.value = fmt.Sprintf("%d", n)
+			}
+		case "email":
+			var v struct {
+				Address string `json:"email_address"`
+			}
+			err = json.Unmarshal(raw, &v)
+			out.value = v.Address
+		case "address":
+			var v map[string]string
+			err = json.Unmarshal(raw, &v)
+			var parts []string
+			for _, k := range []string{"street", "city", "state", "zip", "country"} {
+				if v[k] != "" {
+					parts = append(parts, v[k])
+				}
+			}
+			out.value = strings.Join(parts, ", ")
+		case "file":
+			out.file = new(FileValue)
+			err = json.Unmarshal(raw, out.file)
+		default:
+			out.value = strings.Trim(string(raw), `"`)
+			if out.value == "null" {
+				out.value = ""
+			}
+		}
+		return
+	}
+	return
+}
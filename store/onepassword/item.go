@@ -0,0 +1,166 @@
+package onepassword
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+// SourceItem - 1password item with its vault and archive files
+type SourceItem struct {
+	Item
+	vault string
+	files map[string][]byte
+}
+
+// GetSecretPath - vault/[archive|favorite]/category/title
+func (i SourceItem) GetSecretPath() (out string, err error) {
+	if vault := utils.Transliterate(i.vault); vault != "" {
+		out = filepath.Join(out, vault)
+	}
+
+	switch {
+	case i.IsArchived():
+		out = filepath.Join(out, "archive")
+	case i.IsFavorite():
+		out = filepath.Join(out, "favorite")
+	}
+
+	out = filepath.Join(out, i.GetCategoryPath())
+
+	var title = utils.Transliterate(i.Overview.Title)
+	if title == "" {
+		return "", errors.New("title cannot be empty")
+	}
+
+	return filepath.Join(out, title), nil
+}
+
+func (i SourceItem) attachment(f *FileValue) (field.FieldInterface, error) {
+	data, ok := i.files[f.DocumentID]
+	if !ok {
+		return nil, fmt.Errorf("file '%s' is not found in archive", f.FileName)
+	}
+	return field.NewAttachmentField(f.FileName, data), nil
+}
+
+// username - value of login field designated as username
+func (i SourceItem) username() string {
+	for _, f := range i.Details.LoginFields {
+		if f.Designation == "username" && f.Value != "" {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// GetFields -
+func (i SourceItem) GetFields() (out []field.FieldInterface, err error) {
+	var o = i.Overview
+	if o.Title != "" {
+		out = append(out, field.NewTitleField("", o.Title))
+	}
+
+	out = append(out, field.NewIDField("onepassword_uuid", i.UUID))
+	out = append(out, field.NewSimpleField("category", []byte(i.GetCategoryPath()), false, false))
+
+	if v := o.Subtitle; v != "" {
+		out = append(out, field.NewUsernameField("subtitle", v))
+	}
+
+	var urls []string
+	if o.URL != "" {
+		urls = append(urls, o.URL)
+	}
+	for _, u := range o.Urls {
+		if u.URL != "" && !utils.InList(urls, u.URL) {
+			urls = append(urls, u.URL)
+		}
+	}
+	for n, u := range urls {
+		var k = "url"
+		if n > 0 {
+			k = fmt.Sprintf("url_%d", n+1)
+		}
+		out = append(out, field.NewUrlField(k, u))
+	}
+
+	if len(o.Tags) > 0 {
+		out = append(out, field.NewTagsField("", fmt.Sprintf("[%s]", strings.Join(o.Tags, ", "))))
+	}
+
+	var d = i.Details
+	for _, f := range d.LoginFields {
+		if f.Value == "" {
+			continue
+		}
+
+		switch f.Designation {
+		case "username":
+			out = append(out, field.NewUsernameField("", f.Value))
+		case "password":
+			out = append(out, field.NewPasswordField("", f.Value))
+		default:
+			var label = utils.Transliterate(utils.FirstNonEmpty(f.Name, f.ID))
+			if label == "" {
+				continue
+			}
+			out = append(out, field.NewSimpleField(label, []byte(f.Value), false, f.FieldType == "P"))
+		}
+	}
+
+	if d.Password != "" {
+		out = append(out, field.NewPasswordField("", d.Password))
+	}
+
+	if d.NotesPlain != "" {
+		out = append(out, field.NewSimpleField("note", []byte(d.NotesPlain), true, false))
+	}
+
+	for _, s := range d.Sections {
+		for _, f := range s.Fields {
+			v, err := f.GetValue()
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode field '%s': %s", f.Title, err.Error())
+			}
+
+			if v.file != nil {
+				ff, err := i.attachment(v.file)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, ff)
+				continue
+			}
+
+			var label = utils.Transliterate(utils.FirstNonEmpty(f.Title, f.ID))
+			if v.totp {
+				if uri, ok := utils.OTPAuthURI(v.value, utils.OTPIssuer(o.Title, o.URL), i.username()); ok {
+					out = append(out, field.NewOTPField("", uri))
+					continue
+				}
+				label = "totp"
+			}
+			if label == "" || v.value == "" {
+				continue
+			}
+
+			var multiline = f.Multiline || strings.Contains(v.value, "\n")
+			out = append(out, field.NewSimpleField(label, []byte(v.value), multiline, v.sensitive))
+		}
+	}
+
+	if d.DocumentAttributes != nil {
+		ff, err := i.attachment(d.DocumentAttributes)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ff)
+	}
+
+	return
+}
@@ -0,0 +1,26 @@
+package onepassword
+
+import "github.com/revengel/enpass2gopass/store"
+
+// SourceName -
+const SourceName = "onePasswordSource"
+
+func init() {
+	store.RegisterSource(store.SourceProvider{
+		Provider: store.Provider{
+			Name:        SourceName,
+			Description: "1Password 1PUX export",
+			FlagPrefix:  "source-1pux",
+			Options: []store.Option{
+				store.StringOption("path", "", "source 1password 1pux export path").Require(),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreSource, error) {
+			s, err := NewOnePasswordSource(opts.String("path"))
+			if err != nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	})
+}
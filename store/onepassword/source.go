@@ -0,0 +1,98 @@
+package onepassword
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/store"
+)
+
+const (
+	exportDataFile = "export.data"
+	filesDir       = "files/"
+)
+
+// OnePasswordSource - reads 1password 1pux archive
+type OnePasswordSource struct {
+	path string
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// LoadData -
+func (s OnePasswordSource) LoadData() (o []store.StoreSourceItem, err error) {
+	zr, err := zip.OpenReader(s.path)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+
+	var exportData []byte
+	// attachments are stored as files/<documentId>__<fileName>
+	var files = make(map[string][]byte)
+	for _, f := range zr.File {
+		switch {
+		case f.Name == exportDataFile:
+			exportData, err = readZipFile(f)
+		case strings.HasPrefix(f.Name, filesDir) && !f.FileInfo().IsDir():
+			var docID, _, _ = strings.Cut(path.Base(f.Name), "__")
+			files[docID], err = readZipFile(f)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if exportData == nil {
+		return nil, errors.New(exportDataFile + " is not found in archive")
+	}
+
+	var e Export
+	err = json.Unmarshal(exportData, &e)
+	if err != nil {
+		return
+	}
+
+	for _, account := range e.Accounts {
+		for _, vault := range account.Vaults {
+			for _, item := range vault.Items {
+				o = append(o, SourceItem{
+					Item:  item,
+					vault: vault.Attrs.Name,
+					files: files,
+				})
+			}
+		}
+	}
+
+	return o, nil
+}
+
+// GetSourcePath -
+func (s OnePasswordSource) GetSourcePath() string {
+	return s.path
+}
+
+// NewOnePasswordSource -
+func NewOnePasswordSource(dataPath string) (o *OnePasswordSource, err error) {
+	absPath, err := filepath.Abs(dataPath)
+	if err != nil {
+		return
+	}
+
+	return &OnePasswordSource{
+		path: absPath,
+	}, nil
+}
@@ -0,0 +1,154 @@
+package onepassword
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+)
+
+// write1pux - packs testdata into 1pux archive, files are stored under files/
+func write1pux(t *testing.T, names ...string) string {
+	t.Helper()
+	var p = filepath.Join(t.TempDir(), "export.1pux")
+	out, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	var zw = zip.NewWriter(out)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func loadItems(t *testing.T, p string) map[string]SourceItem {
+	t.Helper()
+	s, err := NewOnePasswordSource(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := s.LoadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out = make(map[string]SourceItem)
+	for _, item := range items {
+		p, err := item.GetSecretPath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[p] = item.(SourceItem)
+	}
+	return out
+}
+
+func TestOnePasswordSourcePaths(t *testing.T) {
+	var items = loadItems(t, write1pux(t, "export.data", "files/doc1__codes.txt", "files/doc2__scan.bin"))
+	for _, p := range []string{
+		filepath.Join("private", "favorite", "login", "github"),
+		filepath.Join("private", "document", "passport_scan"),
+		filepath.Join("rabota", "archive", "note", "server_notes"),
+	} {
+		if _, ok := items[p]; !ok {
+			t.Errorf("item %s is not found in %v", p, items)
+		}
+	}
+}
+
+func TestOnePasswordSourceFields(t *testing.T) {
+	var items = loadItems(t, write1pux(t, "export.data", "files/doc1__codes.txt", "files/doc2__scan.bin"))
+	fields, err := items[filepath.Join("private", "favorite", "login", "github")].GetFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values = make(map[string]field.FieldInterface)
+	for _, f := range fields {
+		values[f.GetKey()] = f
+	}
+
+	for k, v := range map[string]string{
+		"title":    "GitHub",
+		"username": "octocat",
+		"password": "s3cr3t",
+		"url":      "https://github.com",
+		"url_2":    "https://api.github.com",
+		"tags":     "[dev, work]",
+		"note":     "login note",
+		"pin":      "1234",
+		"email":    "octocat@example.com",
+		"address":  "1 Main St, Springfield, 12345, us",
+		"expires":  "202612",
+		"otpauth":  "otpauth://totp/GitHub:octocat?issuer=GitHub&secret=JBSWY3DPEHPK3PXP",
+	} {
+		if f, ok := values[k]; !ok || f.GetValueString() != v {
+			t.Errorf("field %s: expected %q, got %v", k, v, f)
+		}
+	}
+
+	for k, typ := range map[string]field.FieldType{
+		"password":  field.SecretPasswordField,
+		"url":       field.SecretURLField,
+		"otpauth":   field.SecretOTPField,
+		"codes.txt": field.SecretAttachmentField,
+	} {
+		if f, ok := values[k]; !ok || !f.IsType(typ) {
+			t.Errorf("field %s: expected type %s, got %v", k, typ, f)
+		}
+	}
+
+	if !values["pin"].IsSensitive() {
+		t.Error("concealed field is not sensitive")
+	}
+	if v := values["codes.txt"].GetValueString(); v != "code1 code2" {
+		t.Errorf("unexpected attachment content %q", v)
+	}
+
+	doc, err := items[filepath.Join("private", "document", "passport_scan")].GetFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last = doc[len(doc)-1]
+	if last.GetKey() != "scan.bin" || last.GetValueString() != "\x00\x01\x02\x03" {
+		t.Errorf("document attachment is not read: %v", last)
+	}
+}
+
+func TestOnePasswordSourceMissingFiles(t *testing.T) {
+	var items = loadItems(t, write1pux(t, "export.data"))
+	_, err := items[filepath.Join("private", "document", "passport_scan")].GetFields()
+	if err == nil || !strings.Contains(err.Error(), "not found in archive") {
+		t.Errorf("expected missing file error, got %v", err)
+	}
+
+	s, err := NewOnePasswordSource(write1pux(t, "files/doc1__codes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.LoadData(); err == nil {
+		t.Error("archive without export.data is read")
+	}
+}
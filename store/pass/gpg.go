@@ -0,0 +1,215 @@
+package pass
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const gpgIDFile = ".gpg-id"
+
+// readRecipients - reads .gpg-id file, empty lines and comments are skipped
+func readRecipients(p string) (out []string, err error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var line = strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, s.Err()
+}
+
+// findRecipientsFile - returns nearest .gpg-id file from dir up to store root
+func findRecipientsFile(root, dir string) (string, error) {
+	for {
+		var p = filepath.Join(dir, gpgIDFile)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+
+		if dir == root || !strings.HasPrefix(dir, root) {
+			return "", fmt.Errorf("%s file is not found in password store '%s'", gpgIDFile, root)
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// readKeyRing - reads armored or binary public keyring
+func readKeyRing(data []byte) (openpgp.EntityList, error) {
+	if block, err := armor.Decode(bytes.NewReader(data)); err == nil {
+		return openpgp.ReadKeyRing(block.Body)
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(data))
+}
+
+// exportKeys - exports public keys of recipients from gnupg keyring
+func exportKeys(recipients []string) (openpgp.EntityList, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", append([]string{"--batch", "--export", "--"}, recipients...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot export public keys from gnupg: %s: %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return readKeyRing(out)
+}
+
+// decrypt - decrypts message with gpg, secret keys never leave gnupg and its agent;
+// gpg fails instead of asking for passphrase if key is not unlocked in agent
+func decrypt(data []byte) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", "--quiet", "--batch", "--pinentry-mode", "error", "--decrypt")
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gpg --decrypt: %s: %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// matchRecipient - checks whether recipient (fingerprint, key id or email) identifies entity
+func matchRecipient(e *openpgp.Entity, recipient string) bool {
+	var r = strings.ReplaceAll(recipient, " ", "")
+	r = strings.TrimPrefix(strings.TrimPrefix(r, "0x"), "0X")
+	r = strings.TrimPrefix(r, "!")
+	r = strings.TrimSuffix(r, "!")
+
+	var isHex = len(r) >= 8
+	for _, c := range r {
+		isHex = isHex && strings.ContainsRune("0123456789abcdefABCDEF", c)
+	}
+
+	if isHex {
+		var keys = []*packet.PublicKey{e.PrimaryKey}
+		for _, sk := range e.Subkeys {
+			keys = append(keys, sk.PublicKey)
+		}
+		for _, k := range keys {
+			if strings.HasSuffix(strings.ToUpper(fmt.Sprintf("%X", k.Fingerprint)), strings.ToUpper(r)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var email = strings.ToLower(strings.Trim(recipient, "<> "))
+	for name, id := range e.Identities {
+		if strings.EqualFold(id.UserId.Email, email) || strings.EqualFold(name, recipient) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyRing - public keys of recipients
+type keyRing struct {
+	keys openpgp.EntityList
+	// export - use gnupg keyring when keys are not provided
+	export bool
+}
+
+// entities - returns keys of recipients, every recipient must be resolved
+func (k *keyRing) entities(recipients []string) (out []*openpgp.Entity, err error) {
+	for _, r := range recipients {
+		var found bool
+		for _, e := range k.keys {
+			if matchRecipient(e, r) {
+				found = true
+				out = append(out, e)
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		if !k.export {
+			return nil, fmt.Errorf("public key of recipient '%s' is not found", r)
+		}
+
+		keys, err := exportKeys([]string{r})
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("public key of recipient '%s' is not found in gnupg keyring", r)
+		}
+		k.keys = append(k.keys, keys...)
+		out = append(out, keys...)
+	}
+	return
+}
+
+// encrypt - encrypts data to all entities
+func encrypt(data []byte, to []*openpgp.Entity) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := openpgp.Encrypt(&buf, to, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encryptedTo - returns key ids the message is encrypted to
+func encryptedTo(data []byte) (ids map[uint64]bool, err error) {
+	ids = make(map[uint64]bool)
+	r := packet.NewReader(bytes.NewReader(data))
+	for {
+		p, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch p := p.(type) {
+		case *packet.EncryptedKey:
+			ids[p.KeyId] = true
+		case *packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
+			return ids, nil
+		}
+	}
+}
+
+// encryptionKeyIDs - returns ids of keys used to encrypt message to entities
+func encryptionKeyIDs(to []*openpgp.Entity) map[uint64]bool {
+	var ids = make(map[uint64]bool)
+	for _, e := range to {
+		if k, ok := e.EncryptionKey(time.Now()); ok {
+			ids[k.PublicKey.KeyId] = true
+		}
+	}
+	return ids
+}
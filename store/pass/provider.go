@@ -0,0 +1,28 @@
+package pass
+
+import "github.com/revengel/enpass2gopass/store"
+
+// DestinationName -
+const DestinationName = "passDestination"
+
+func init() {
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        DestinationName,
+			Description: "password-store directory written without gopass",
+			FlagPrefix:  "destination-pass",
+			Options: []store.Option{
+				store.StringOption("path", "", "destination password store directory, PASSWORD_STORE_DIR or ~/.password-store if empty"),
+				store.StringOption("keyring", "", "public keyring with destination password store recipients, gnupg keyring is used if empty"),
+				store.BoolOption("git", false, "commit destination password store changes to git"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			st, err := NewStore(opts.String("path"), opts.String("keyring"), env.Prefix, opts.Bool("git"), env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
@@ -0,0 +1,258 @@
+package pass
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/store/attachment"
+	"github.com/revengel/enpass2gopass/store/secretdir"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const secretExt = ".gpg"
+
+// Store - writes secrets into password-store directory without gopass
+type Store struct {
+	root    string
+	prefix  string
+	keys    *keyRing
+	decrypt bool
+	git     bool
+	items   *utils.UniqueStrings
+	written map[string]bool
+	dryrun  bool
+	logger  *logrus.Logger
+}
+
+// Close - commits changes if git is enabled
+func (st *Store) Close() error {
+	if st.dryrun || !st.git {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(st.root, ".git")); err != nil {
+		st.logger.WithField("path", st.root).Warn("password store is not a git repository, changes are not committed")
+		return nil
+	}
+
+	err := st.runGit("add", "--all", "--", st.prefix)
+	if err != nil {
+		return err
+	}
+
+	// changes of previous runs are committed too if their commit has failed,
+	// changes staged outside of prefix are left to their owner
+	if st.runGit("diff", "--cached", "--quiet", "--", st.prefix) == nil {
+		return nil
+	}
+
+	err = st.runGit("commit", "--quiet", "-m", fmt.Sprintf("Import secrets into %s", st.prefix), "--", st.prefix)
+	if err != nil {
+		return err
+	}
+
+	st.logger.WithField("path", st.root).Info("password store changes have been committed")
+	return nil
+}
+
+func (st *Store) runGit(args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", st.root}, args...)...)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("git %s: %s: %s", args[0], err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// needsUpdate - compares existing file with new content; content is compared
+// only if file is encrypted to the same keys and gpg can decrypt it
+func (st *Store) needsUpdate(p string, data []byte, keyIDs map[uint64]bool) bool {
+	current, err := os.ReadFile(p)
+	if err != nil {
+		return true
+	}
+
+	ids, err := encryptedTo(current)
+	if err != nil || !reflect.DeepEqual(ids, keyIDs) {
+		return true
+	}
+
+	if !st.decrypt {
+		return true
+	}
+
+	plain, err := decrypt(current)
+	if err != nil {
+		st.logger.WithField("passkey", p).Debugf("existing secret cannot be decrypted and will be rewritten: %s", err.Error())
+		return true
+	}
+
+	return !bytes.Equal(plain, data)
+}
+
+// saveSecret - encrypts data to recipients of nearest .gpg-id and writes it
+func (st *Store) saveSecret(data []byte, name string) (bool, error) {
+	var p = filepath.Join(st.root, filepath.FromSlash(name)+secretExt)
+	st.written[p] = true
+	var l = st.logger.WithField("passkey", name)
+
+	gpgID, err := findRecipientsFile(st.root, filepath.Dir(p))
+	if err != nil {
+		return false, err
+	}
+
+	recipients, err := readRecipients(gpgID)
+	if err != nil {
+		return false, err
+	}
+	if len(recipients) == 0 {
+		return false, fmt.Errorf("%s has no recipients", gpgID)
+	}
+
+	to, err := st.keys.entities(recipients)
+	if err != nil {
+		return false, err
+	}
+
+	if !st.needsUpdate(p, data, encryptionKeyIDs(to)) {
+		l.Debug("pass secret already in actual state")
+		return false, nil
+	}
+
+	l.Info("secret will be updated")
+	if st.dryrun {
+		return true, nil
+	}
+
+	encrypted, err := encrypt(data, to)
+	if err != nil {
+		return false, fmt.Errorf("cannot encrypt secret: %s", err.Error())
+	}
+
+	err = secretdir.WriteFile(p, encrypted)
+	if err != nil {
+		return false, err
+	}
+
+	l.Info("secret has been updated")
+	return true, nil
+}
+
+// Save - secrets use the same layout as gopass destination
+func (st *Store) Save(fields []field.FieldInterface, p string) (bool, error) {
+	p = st.items.Unique(p)
+
+	var secretFields []field.FieldInterface
+	var attachments = make(map[string]*secrets.AKV)
+	for _, f := range fields {
+		if !f.IsType(field.SecretAttachmentField) {
+			secretFields = append(secretFields, f)
+			continue
+		}
+
+		secret, err := attachment.NewSecret(f.GetKey(), f.GetValue())
+		if err != nil {
+			return false, err
+		}
+		attachments[f.GetKey()] = secret
+	}
+
+	mainSecret, err := secretdir.AKVSecret(secretFields)
+	if err != nil {
+		return false, err
+	}
+
+	out, err := st.saveSecret(mainSecret.Bytes(), secretdir.MainPath(st.prefix, p))
+	if err != nil {
+		return out, err
+	}
+
+	for name, secret := range attachments {
+		changed, err := st.saveSecret(secret.Bytes(), secretdir.AttachmentPath(st.prefix, p, name))
+		if err != nil {
+			return out, err
+		}
+		out = out || changed
+	}
+
+	return out, nil
+}
+
+// Cleanup - removes secrets under prefix which were not saved during this run
+func (st *Store) Cleanup() (bool, error) {
+	return secretdir.Cleanup(st.root, st.prefix, secretExt, st.written, st.dryrun, func(name string) {
+		st.logger.WithField("type", "cleaner").
+			WithField("passkey", name).
+			Info("pass secret will be deleted")
+	})
+}
+
+// NewStore - storePath defaults to PASSWORD_STORE_DIR or ~/.password-store;
+// recipients keys are read from keyring file or exported from gnupg keyring if it is empty,
+// existing secrets are decrypted with gpg to skip unchanged ones
+func NewStore(storePath, keyringPath, prefix string, git, dryrun bool, logger *logrus.Logger) (st *Store, err error) {
+	if storePath == "" {
+		storePath = os.Getenv("PASSWORD_STORE_DIR")
+	}
+	if storePath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		storePath = filepath.Join(home, ".password-store")
+	}
+
+	root, err := filepath.Abs(storePath)
+	if err != nil {
+		return
+	}
+
+	if _, err = os.Stat(filepath.Join(root, gpgIDFile)); err != nil {
+		return nil, fmt.Errorf("password store '%s' is not initialized: %s", root, err.Error())
+	}
+
+	var keys = &keyRing{export: keyringPath == ""}
+	if keyringPath != "" {
+		data, err := os.ReadFile(keyringPath)
+		if err != nil {
+			return nil, err
+		}
+
+		keys.keys, err = readKeyRing(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read keyring '%s': %s", keyringPath, err.Error())
+		}
+	}
+
+	_, err = exec.LookPath("gpg")
+	var canDecrypt = err == nil
+	if !canDecrypt {
+		logger.Debug("gpg is not installed, existing secrets will not be compared")
+	}
+
+	if prefix == "" {
+		prefix = "enpass"
+	}
+
+	return &Store{
+		root:    root,
+		prefix:  prefix,
+		keys:    keys,
+		decrypt: canDecrypt,
+		git:     git,
+		items:   utils.NewUniqueStrings(logger),
+		written: make(map[string]bool),
+		dryrun:  dryrun,
+		logger:  logger,
+	}, nil
+}
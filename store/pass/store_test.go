@@ -0,0 +1,209 @@
+package pass
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Logger {
+	var l = logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+// testKeys - generates throwaway key, writes its public keyring and returns secret key
+func testKeys(t *testing.T, email string) (public string, secret []byte) {
+	t.Helper()
+	e, err := openpgp.NewEntity("test", "", email, &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pub, sec bytes.Buffer
+	if err = e.Serialize(&pub); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.SerializePrivate(&sec, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	public = filepath.Join(t.TempDir(), "public.gpg")
+	if err = os.WriteFile(public, pub.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return public, sec.Bytes()
+}
+
+// testGnupg - makes empty gnupg home with secret key the only one gpg can decrypt with
+func testGnupg(t *testing.T, secret []byte) {
+	t.Helper()
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	var home = t.TempDir()
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+	})
+
+	cmd := exec.Command("gpg", "--batch", "--quiet", "--import")
+	cmd.Stdin = bytes.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("cannot import secret key: %s: %s", err.Error(), out)
+	}
+}
+
+// testRoot - initializes password store for recipient
+func testRoot(t *testing.T, recipient string) string {
+	t.Helper()
+	var root = t.TempDir()
+	err := os.WriteFile(filepath.Join(root, gpgIDFile), []byte(recipient+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func itemFields(password string) []field.FieldInterface {
+	return []field.FieldInterface{
+		field.NewUsernameField("", "user"),
+		field.NewPasswordField("", password),
+		field.NewAttachmentField("file.txt", []byte("content")),
+	}
+}
+
+func save(t *testing.T, st *Store, fields []field.FieldInterface, p string) bool {
+	t.Helper()
+	changed, err := st.Save(fields, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return changed
+}
+
+func TestStoreComparesDecryptedSecrets(t *testing.T) {
+	public, secret := testKeys(t, "test@example.com")
+	testGnupg(t, secret)
+	var root = testRoot(t, "test@example.com")
+
+	st, err := NewStore(root, public, "enpass", false, false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !save(t, st, itemFields("secret"), "login/github") {
+		t.Fatal("new secret is not reported as changed")
+	}
+
+	var p = filepath.Join(root, "enpass", "login", "github", "data"+secretExt)
+	encrypted, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(plain), "secret\n") || !strings.Contains(string(plain), "username: user") {
+		t.Errorf("unexpected secret content: %q", plain)
+	}
+
+	st, err = NewStore(root, public, "enpass", false, false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if save(t, st, itemFields("secret"), "login/github") {
+		t.Error("unchanged secret is reported as changed")
+	}
+	if current, _ := os.ReadFile(p); !bytes.Equal(current, encrypted) {
+		t.Error("unchanged secret has been rewritten")
+	}
+
+	st, err = NewStore(root, public, "enpass", false, false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !save(t, st, itemFields("changed"), "login/github") {
+		t.Error("changed secret is not reported as changed")
+	}
+}
+
+func TestStoreRewritesSecretsWithoutKey(t *testing.T) {
+	public, _ := testKeys(t, "test@example.com")
+	_, other := testKeys(t, "other@example.com")
+	testGnupg(t, other)
+	var root = testRoot(t, "test@example.com")
+
+	st, err := NewStore(root, public, "enpass", false, false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	save(t, st, itemFields("secret"), "login/github")
+
+	// existing secret cannot be decrypted by gpg without its key and is rewritten
+	st, err = NewStore(root, public, "enpass", false, false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !save(t, st, itemFields("secret"), "login/github") {
+		t.Error("secret which cannot be decrypted is not rewritten")
+	}
+}
+
+func TestStoreCommitsOnlyPrefix(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	public, _ := testKeys(t, "test@example.com")
+	var root = testRoot(t, "test@example.com")
+	var git = func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", root}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %s: %s", args[0], err.Error(), out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "--quiet")
+	git("config", "user.name", "test")
+	git("config", "user.email", "test@example.com")
+	git("add", gpgIDFile)
+	git("commit", "--quiet", "-m", "init")
+
+	// change staged outside of prefix must not be committed by store
+	err := os.WriteFile(filepath.Join(root, "other.gpg"), []byte("other"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	git("add", "other.gpg")
+
+	st, err := NewStore(root, public, "enpass", true, false, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	save(t, st, itemFields("secret"), "login/github")
+
+	var committed = strings.Fields(git("show", "--name-only", "--format=", "HEAD"))
+	var expected = []string{"enpass/login/github/attachments/file.txt.gpg", "enpass/login/github/data.gpg"}
+	if strings.Join(committed, " ") != strings.Join(expected, " ") {
+		t.Errorf("committed files = %v, expected %v", committed, expected)
+	}
+	if staged := git("diff", "--cached", "--name-only"); staged != "other.gpg" {
+		t.Errorf("staged files = %q, expected other.gpg", staged)
+	}
+}
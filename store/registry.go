@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// OptionType - value type of provider option
+type OptionType string
+
+const (
+	// OptionString -
+	OptionString OptionType = "string"
+	// OptionStrings - comma separated or repeated values
+	OptionStrings OptionType = "strings"
+	// OptionBool -
+	OptionBool OptionType = "bool"
+	// OptionInt -
+	OptionInt OptionType = "int"
+	// OptionUint32 -
+	OptionUint32 OptionType = "uint32"
+	// OptionUint64 -
+	OptionUint64 OptionType = "uint64"
+)
+
+// Option - typed provider setting, it is exposed as flag <provider flag prefix>-<name>
+type Option struct {
+	Name        string
+	Type        OptionType
+	Default     interface{}
+	Description string
+	Required    bool
+}
+
+// Require - returns required copy of option
+func (o Option) Require() Option {
+	o.Required = true
+	return o
+}
+
+// StringOption -
+func StringOption(name, def, description string) Option {
+	return Option{Name: name, Type: OptionString, Default: def, Description: description}
+}
+
+// StringsOption -
+func StringsOption(name string, def []string, description string) Option {
+	return Option{Name: name, Type: OptionStrings, Default: def, Description: description}
+}
+
+// BoolOption -
+func BoolOption(name string, def bool, description string) Option {
+	return Option{Name: name, Type: OptionBool, Default: def, Description: description}
+}
+
+// IntOption -
+func IntOption(name string, def int, description string) Option {
+	return Option{Name: name, Type: OptionInt, Default: def, Description: description}
+}
+
+// Uint32Option -
+func Uint32Option(name string, def uint32, description string) Option {
+	return Option{Name: name, Type: OptionUint32, Default: def, Description: description}
+}
+
+// Uint64Option -
+func Uint64Option(name string, def uint64, description string) Option {
+	return Option{Name: name, Type: OptionUint64, Default: def, Description: description}
+}
+
+// Options - option values of single provider, they are looked up by option name
+type Options interface {
+	String(name string) string
+	Strings(name string) []string
+	Bool(name string) bool
+	Int(name string) int
+	Uint32(name string) uint32
+	Uint64(name string) uint64
+	// Changed - checks whether option is set explicitly
+	Changed(name string) bool
+}
+
+// Env - settings shared by all providers of run
+type Env struct {
+	Ctx    context.Context
+	Prefix string
+	DryRun bool
+	Logger *logrus.Logger
+	// Source - source provider name, destinations can record it with source file and its sha256
+	Source     string
+	SourceFile string
+}
+
+// SourceSummary - source description for commit messages and reports;
+// source file is hashed only when summary is requested
+func (e Env) SourceSummary() (string, error) {
+	if e.Source == "" {
+		return "", nil
+	}
+
+	var lines = []string{"Source: " + e.Source}
+	if e.SourceFile != "" {
+		hash, err := utils.GetFileHash(e.SourceFile)
+		if err != nil {
+			return "", fmt.Errorf("cannot hash source file: %s", err.Error())
+		}
+		lines = append(lines, "Source file: "+filepath.Base(e.SourceFile), "Source sha256: "+hash)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Provider - self description of source or destination
+type Provider struct {
+	Name        string
+	Description string
+	// FlagPrefix - prefix of option flags, e.g. source-keepass
+	FlagPrefix string
+	Options    []Option
+}
+
+// Flag - flag name of provider option
+func (p Provider) Flag(name string) string {
+	return p.FlagPrefix + "-" + name
+}
+
+// Validate - checks that all required options are set
+func (p Provider) Validate(opts Options) error {
+	for _, o := range p.Options {
+		if !o.Required {
+			continue
+		}
+
+		var set bool
+		switch o.Type {
+		case OptionString:
+			set = opts.String(o.Name) != ""
+		case OptionStrings:
+			set = len(opts.Strings(o.Name)) > 0
+		default:
+			set = opts.Changed(o.Name)
+		}
+
+		if !set {
+			return fmt.Errorf("option --%s is required by %s provider", p.Flag(o.Name), p.Name)
+		}
+	}
+	return nil
+}
+
+// SourceProvider -
+type SourceProvider struct {
+	Provider
+	New func(env Env, opts Options) (StoreSource, error)
+}
+
+// DestinationProvider -
+type DestinationProvider struct {
+	Provider
+	New func(env Env, opts Options) (StoreDestination, error)
+}
+
+var (
+	sources      = make(map[string]SourceProvider)
+	destinations = make(map[string]DestinationProvider)
+)
+
+// RegisterSource - is called from init of provider package
+func RegisterSource(p SourceProvider) {
+	if _, ok := sources[p.Name]; ok {
+		panic(fmt.Sprintf("source provider %s is registered twice", p.Name))
+	}
+	sources[p.Name] = p
+}
+
+// RegisterDestination - is called from init of provider package
+func RegisterDestination(p DestinationProvider) {
+	if _, ok := destinations[p.Name]; ok {
+		panic(fmt.Sprintf("destination provider %s is registered twice", p.Name))
+	}
+	destinations[p.Name] = p
+}
+
+// GetSource -
+func GetSource(name string) (SourceProvider, bool) {
+	p, ok := sources[name]
+	return p, ok
+}
+
+// GetDestination -
+func GetDestination(name string) (DestinationProvider, bool) {
+	p, ok := destinations[name]
+	return p, ok
+}
+
+// Sources - registered source providers sorted by name
+func Sources() (out []SourceProvider) {
+	for _, p := range sources {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return
+}
+
+// Destinations - registered destination providers sorted by name
+func Destinations() (out []DestinationProvider) {
+	for _, p := range destinations {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvSourceSummary(t *testing.T) {
+	var p = filepath.Join(t.TempDir(), "export.json")
+	err := os.WriteFile(p, []byte("content"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// commit messages must not leak local directories
+	out, err := Env{Source: "enpassJsonSource", SourceFile: p}.SourceSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = "Source: enpassJsonSource\nSource file: export.json\n" +
+		"Source sha256: ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+	if out != expected {
+		t.Errorf("SourceSummary = %q, expected %q", out, expected)
+	}
+
+	out, err = Env{}.SourceSummary()
+	if err != nil || out != "" {
+		t.Errorf("SourceSummary without source = %q, %v, expected empty", out, err)
+	}
+
+	_, err = Env{Source: "enpassJsonSource", SourceFile: p + ".missing"}.SourceSummary()
+	if err == nil {
+		t.Error("missing source file is not reported")
+	}
+}
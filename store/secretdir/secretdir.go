@@ -0,0 +1,148 @@
+package secretdir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/revengel/enpass2gopass/field"
+)
+
+// layout and content of secrets shared by gopass destination and destinations
+// which keep every secret as encrypted file in password-store directory
+
+// MainPath - name of item secret with its fields
+func MainPath(prefix, p string) string {
+	return filepath.ToSlash(filepath.Join(prefix, p, "data"))
+}
+
+// AttachmentPath - name of item attachment secret
+func AttachmentPath(prefix, p, name string) string {
+	return filepath.ToSlash(filepath.Join(prefix, p, "attachments", name))
+}
+
+// AKVSecret - fields as key-value lines, multiline fields are written
+// in the end of secret as "key\n\nvalue" blocks; source ids are not written,
+// secrets are matched by path
+func AKVSecret(fields []field.FieldInterface) (*secrets.AKV, error) {
+	var secret = secrets.NewAKV()
+	var multiline strings.Builder
+	for _, f := range fields {
+		switch {
+		case f.IsType(field.SecretIDField):
+			continue
+		case f.IsType(field.SecretPasswordField) && secret.Password() == "":
+			secret.SetPassword(f.GetValueString())
+			continue
+		case f.GetValueString() == "":
+			continue
+		case f.IsMultiline():
+			if multiline.Len() == 0 {
+				multiline.WriteString("---\n")
+			}
+			fmt.Fprintf(&multiline, "%s\n\n%s\n", f.GetKey(), f.GetValueString())
+			continue
+		}
+
+		err := secret.Set(f.GetKey(), f.GetValueString())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if multiline.Len() > 0 {
+		_, err := secret.Write([]byte(multiline.String()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return secret, nil
+}
+
+// WriteFile - writes file through temporary file in the same directory,
+// so readers see either old or new content; missing directories are created
+func WriteFile(p string, data []byte) (err error) {
+	err = os.MkdirAll(filepath.Dir(p), 0700)
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return
+	}
+
+	var tmpPath = tmp.Name()
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		return
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		return
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return
+	}
+
+	return os.Rename(tmpPath, p)
+}
+
+// Cleanup - removes files with extension under prefix of root which were not
+// written during this run, directories which became empty are removed too;
+// deleted reports name of every stale secret before it is removed
+func Cleanup(root, prefix, ext string, written map[string]bool, dryrun bool, deleted func(name string)) (bool, error) {
+	var stale []string
+	err := filepath.Walk(filepath.Join(root, prefix), func(p string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasSuffix(p, ext) && !written[p] {
+			stale = append(stale, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range stale {
+		name, _ := filepath.Rel(root, strings.TrimSuffix(p, ext))
+		deleted(filepath.ToSlash(name))
+
+		if dryrun {
+			continue
+		}
+
+		err = os.Remove(p)
+		if err != nil {
+			return false, err
+		}
+
+		for dir := filepath.Dir(p); dir != root; dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+
+	return len(stale) > 0 && !dryrun, nil
+}
@@ -0,0 +1,187 @@
+package sops
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+)
+
+const configFile = ".sops.yaml"
+
+// creationRule - rule of .sops.yaml, only age keys are supported
+type creationRule struct {
+	PathRegex         string      `yaml:"path_regex"`
+	Age               interface{} `yaml:"age"`
+	EncryptedRegex    string      `yaml:"encrypted_regex"`
+	UnencryptedSuffix string      `yaml:"unencrypted_suffix"`
+}
+
+// config - .sops.yaml content
+type config struct {
+	CreationRules []creationRule `yaml:"creation_rules"`
+	dir           string
+}
+
+// rule - encryption settings of single file
+type rule struct {
+	recipients        []age.Recipient
+	recipientStrings  []string
+	encryptedRegex    *regexp.Regexp
+	unencryptedSuffix string
+}
+
+// encrypted - checks whether value with path has to be encrypted
+func (r rule) encrypted(path []string) bool {
+	if r.encryptedRegex != nil {
+		for _, k := range path {
+			if r.encryptedRegex.MatchString(k) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var suffix = r.unencryptedSuffix
+	for _, k := range path {
+		if strings.HasSuffix(k, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// findConfig - looks for .sops.yaml from dir up to filesystem root
+func findConfig(dir string) string {
+	for {
+		var p = filepath.Join(dir, configFile)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+
+		var parent = filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadConfig -
+func loadConfig(p string) (c *config, err error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return
+	}
+
+	c = &config{dir: filepath.Dir(p)}
+	err = yaml.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse sops config '%s': %s", p, err.Error())
+	}
+	return c, nil
+}
+
+// parseRecipients - parses comma separated string or list of age recipients
+func parseRecipients(v interface{}) (out []string) {
+	switch v := v.(type) {
+	case string:
+		for _, r := range strings.Split(v, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				out = append(out, r)
+			}
+		}
+	case []interface{}:
+		for _, r := range v {
+			out = append(out, parseRecipients(r)...)
+		}
+	}
+	return
+}
+
+// newRule -
+func newRule(recipients []string, encryptedRegex, unencryptedSuffix string) (r rule, err error) {
+	if len(recipients) == 0 {
+		return r, errors.New("sops age recipients are not set")
+	}
+
+	for _, s := range recipients {
+		rcpt, err := age.ParseX25519Recipient(s)
+		if err != nil {
+			return r, fmt.Errorf("invalid age recipient '%s': %s", s, err.Error())
+		}
+		r.recipients = append(r.recipients, rcpt)
+		r.recipientStrings = append(r.recipientStrings, s)
+	}
+
+	if encryptedRegex != "" {
+		r.encryptedRegex, err = regexp.Compile(encryptedRegex)
+		if err != nil {
+			return r, fmt.Errorf("invalid sops encrypted_regex: %s", err.Error())
+		}
+	}
+
+	r.unencryptedSuffix = unencryptedSuffix
+	if r.encryptedRegex == nil && r.unencryptedSuffix == "" {
+		r.unencryptedSuffix = "_unencrypted"
+	}
+
+	return r, nil
+}
+
+// ruleFor - returns first creation rule matching file path relative to config directory
+func (c *config) ruleFor(p string) (r rule, err error) {
+	rel, err := filepath.Rel(c.dir, p)
+	if err != nil {
+		rel = p
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, cr := range c.CreationRules {
+		if cr.PathRegex != "" {
+			re, err := regexp.Compile(cr.PathRegex)
+			if err != nil {
+				return r, fmt.Errorf("invalid sops path_regex '%s': %s", cr.PathRegex, err.Error())
+			}
+			if !re.MatchString(rel) {
+				continue
+			}
+		}
+
+		return newRule(parseRecipients(cr.Age), cr.EncryptedRegex, cr.UnencryptedSuffix)
+	}
+
+	return r, fmt.Errorf("no sops creation rule matches '%s'", rel)
+}
+
+// loadIdentities - reads age identities like sops does, they are optional
+// and used only to compare existing files with new content
+func loadIdentities() (out []age.Identity) {
+	var sources []string
+	if v := os.Getenv("SOPS_AGE_KEY"); v != "" {
+		sources = append(sources, v)
+	}
+
+	var keyFile = os.Getenv("SOPS_AGE_KEY_FILE")
+	if keyFile == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			keyFile = filepath.Join(dir, "sops", "age", "keys.txt")
+		}
+	}
+	if data, err := os.ReadFile(keyFile); err == nil {
+		sources = append(sources, string(data))
+	}
+
+	for _, s := range sources {
+		ids, err := age.ParseIdentities(strings.NewReader(s))
+		if err == nil {
+			out = append(out, ids...)
+		}
+	}
+	return
+}
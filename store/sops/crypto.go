@@ -0,0 +1,127 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// sops value encryption: AES256-GCM with 32 bytes nonce, tree path as additional data
+
+const nonceSize = 32
+
+var encValueRe = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, nonceSize)
+}
+
+// encryptValue - encrypts string value into sops ENC[...] format
+func encryptValue(value string, key []byte, aad string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	var iv = make([]byte, nonceSize)
+	_, err = rand.Read(iv)
+	if err != nil {
+		return "", err
+	}
+
+	var sealed = gcm.Seal(nil, iv, []byte(value), []byte(aad))
+	var data, tag = sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+	), nil
+}
+
+// decryptValue - decrypts sops ENC[...] value
+func decryptValue(value string, key []byte, aad string) (string, error) {
+	var m = encValueRe.FindStringSubmatch(value)
+	if m == nil {
+		return "", errors.New("invalid sops encrypted value")
+	}
+
+	var raw [3][]byte
+	for i := range raw {
+		b, err := base64.StdEncoding.DecodeString(m[i+1])
+		if err != nil {
+			return "", err
+		}
+		raw[i] = b
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := gcm.Open(nil, raw[1], append(raw[0], raw[2]...), []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// computeMac - sops mac is uppercase hex of sha512 over all values in tree order
+func computeMac(values []string) string {
+	var h = sha512.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+	}
+	return fmt.Sprintf("%X", h.Sum(nil))
+}
+
+// encryptDataKey - encrypts data key to age recipient in armored form
+func encryptDataKey(key []byte, recipient age.Recipient) (string, error) {
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, recipient)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = w.Write(key)
+	if err != nil {
+		return "", err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = aw.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// decryptDataKey - decrypts armored data key with any of identities
+func decryptDataKey(enc string, identities []age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(enc)), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
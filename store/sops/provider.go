@@ -0,0 +1,34 @@
+package sops
+
+import "github.com/revengel/enpass2gopass/store"
+
+// DestinationName -
+const DestinationName = "sopsDestination"
+
+func init() {
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        DestinationName,
+			Description: "SOPS encrypted YAML files or Kubernetes Secrets for GitOps repositories",
+			FlagPrefix:  "destination-sops",
+			Options: []store.Option{
+				store.StringOption("path", "", "destination directory for sops encrypted files").Require(),
+				store.StringOption("config", "", "sops config with creation rules, .sops.yaml is looked up from destination directory if empty"),
+				store.StringsOption("age-recipients", nil, "age recipients, override sops config creation rules"),
+				store.StringOption("encrypted-regex", "", "regex of keys to encrypt when age recipients are set, all values are encrypted if empty"),
+				store.StringOption("layout", LayoutItem, "destination sops file layout: item (file per secret) or folder (file per folder)"),
+				store.StringOption("format", FormatYAML, "destination sops document format: yaml or kubernetes (Secret manifest)"),
+				store.StringOption("filter", "", "regex of secret paths written to sops destination"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			st, err := NewStore(opts.String("path"), opts.String("config"), opts.Strings("age-recipients"),
+				opts.String("encrypted-regex"), opts.String("layout"), opts.String("format"), opts.String("filter"),
+				env.Prefix, env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
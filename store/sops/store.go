@@ -0,0 +1,406 @@
+package sops
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// LayoutItem - one file per item
+	LayoutItem = "item"
+	// LayoutFolder - one file per folder with items as top level keys
+	LayoutFolder = "folder"
+
+	// FormatYAML - plain yaml document
+	FormatYAML = "yaml"
+	// FormatKubernetes - kubernetes Secret manifest
+	FormatKubernetes = "kubernetes"
+
+	// kubernetesEncryptedRegex - encrypt only secret data like flux and argocd docs suggest
+	kubernetesEncryptedRegex = "^(data|stringData)$"
+
+	fileExt = ".yaml"
+)
+
+var (
+	kubernetesKeyRe  = regexp.MustCompile(`[^-._a-zA-Z0-9]+`)
+	kubernetesNameRe = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// keyValue -
+type keyValue struct {
+	key   string
+	value string
+}
+
+// item - fields of saved secret
+type item struct {
+	name        string
+	fields      []keyValue
+	attachments []keyValue
+}
+
+// document - content of single output file
+type document struct {
+	name  string
+	items []item
+}
+
+// Store - writes sops encrypted yaml files
+type Store struct {
+	root       string
+	prefix     string
+	layout     string
+	format     string
+	filter     *regexp.Regexp
+	config     *config
+	rule       *rule
+	identities []age.Identity
+	docs       map[string]*document
+	order      []string
+	items      *utils.UniqueStrings
+	dryrun     bool
+	logger     *logrus.Logger
+}
+
+// uniqueKey - returns key which is not used in kvs yet
+func uniqueKey(kvs []keyValue, k string) string {
+	var out = k
+	for i := 2; ; i++ {
+		var used bool
+		for _, kv := range kvs {
+			used = used || kv.key == out
+		}
+		if !used {
+			return out
+		}
+		out = fmt.Sprintf("%s_%d", k, i)
+	}
+}
+
+// Save -
+func (st *Store) Save(fields []field.FieldInterface, p string) (bool, error) {
+	p = filepath.ToSlash(p)
+	if st.filter != nil && !st.filter.MatchString(p) {
+		st.logger.WithField("sopskey", p).Debug("secret does not match sops filter and is skipped")
+		return false, nil
+	}
+
+	p = st.items.Unique(p)
+	var it = item{name: path.Base(p)}
+	for _, f := range fields {
+		var k, v = f.GetKey(), f.GetValueString()
+		if f.IsType(field.SecretAttachmentField) {
+			it.attachments = append(it.attachments, keyValue{
+				key:   uniqueKey(it.attachments, k),
+				value: base64.StdEncoding.EncodeToString(f.GetValue()),
+			})
+			continue
+		}
+
+		if k == "" || v == "" {
+			continue
+		}
+		it.fields = append(it.fields, keyValue{key: uniqueKey(it.fields, k), value: v})
+	}
+
+	var name = p
+	if st.layout == LayoutFolder {
+		name = path.Dir(p)
+		if name == "." {
+			name = ""
+		}
+	}
+
+	var file = filepath.Join(st.root, st.prefix, filepath.FromSlash(name)) + fileExt
+	doc, ok := st.docs[file]
+	if !ok {
+		doc = &document{name: path.Join(st.prefix, name)}
+		st.docs[file] = doc
+		st.order = append(st.order, file)
+	}
+	doc.items = append(doc.items, it)
+
+	return true, nil
+}
+
+// itemTree - item fields as mapping, attachments are nested
+func itemTree(it item) *yaml.Node {
+	var n = mapping()
+	for _, kv := range it.fields {
+		setKey(n, kv.key, scalar(kv.value))
+	}
+
+	if len(it.attachments) > 0 {
+		var a = mapping()
+		for _, kv := range it.attachments {
+			setKey(a, kv.key, scalar(kv.value))
+		}
+		setKey(n, "attachments", a)
+	}
+	return n
+}
+
+// tree - builds plain document tree in configured format
+func (st *Store) tree(doc *document) *yaml.Node {
+	if st.format == FormatKubernetes {
+		var stringData, data = mapping(), mapping()
+		for _, it := range doc.items {
+			var keyPrefix string
+			if st.layout == LayoutFolder {
+				keyPrefix = it.name + "."
+			}
+			for _, kv := range it.fields {
+				setKey(stringData, kubernetesKeyRe.ReplaceAllString(keyPrefix+kv.key, "_"), scalar(kv.value))
+			}
+			for _, kv := range it.attachments {
+				setKey(data, kubernetesKeyRe.ReplaceAllString(keyPrefix+kv.key, "_"), scalar(kv.value))
+			}
+		}
+
+		var name = strings.Trim(kubernetesNameRe.ReplaceAllString(strings.ToLower(doc.name), "-"), "-")
+		var root = mapping(
+			"apiVersion", "v1",
+			"kind", "Secret",
+			"metadata", mapping("name", utils.TruncStr(name, 253)),
+			"type", "Opaque",
+		)
+		if len(stringData.Content) > 0 {
+			setKey(root, "stringData", stringData)
+		}
+		if len(data.Content) > 0 {
+			setKey(root, "data", data)
+		}
+		return root
+	}
+
+	if st.layout == LayoutItem && len(doc.items) == 1 {
+		return itemTree(doc.items[0])
+	}
+
+	var root = mapping()
+	for _, it := range doc.items {
+		setKey(root, it.name, itemTree(it))
+	}
+	return root
+}
+
+// ruleFor - returns encryption settings of file
+func (st *Store) ruleFor(file string) (rule, error) {
+	if st.rule != nil {
+		return *st.rule, nil
+	}
+	return st.config.ruleFor(file)
+}
+
+// actual - checks whether existing file has the same content
+func (st *Store) actual(file string, plain *yaml.Node) bool {
+	data, err := os.ReadFile(file)
+	if err != nil || len(st.identities) == 0 {
+		return false
+	}
+
+	var existing yaml.Node
+	err = yaml.Unmarshal(data, &existing)
+	if err == nil {
+		err = decryptTree(&existing, st.identities)
+	}
+	if err != nil {
+		st.logger.WithField("path", file).Debugf("existing sops file cannot be decrypted: %s", err.Error())
+		return false
+	}
+
+	a, errA := flatten(&existing)
+	b, errB := flatten(plain)
+	return errA == nil && errB == nil && reflect.DeepEqual(a, b)
+}
+
+// writeDocument - encrypts and writes document if its content has been changed
+func (st *Store) writeDocument(file string, doc *document) error {
+	var l = st.logger.WithField("sopskey", doc.name)
+	var root = st.tree(doc)
+	if st.actual(file, root) {
+		l.Debug("sops file already in actual state")
+		return nil
+	}
+
+	l.Info("secret will be updated")
+	if st.dryrun {
+		return nil
+	}
+
+	r, err := st.ruleFor(file)
+	if err != nil {
+		return err
+	}
+
+	err = encryptTree(root, r)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt '%s': %s", file, err.Error())
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(4)
+	err = enc.Encode(root)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return err
+	}
+
+	var tmpPath = file + ".tmp"
+	err = os.WriteFile(tmpPath, buf.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, file)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	l.Info("secret has been updated")
+	return nil
+}
+
+// Close - writes all collected documents
+func (st *Store) Close() error {
+	for _, file := range st.order {
+		err := st.writeDocument(file, st.docs[file])
+		if err != nil {
+			return err
+		}
+	}
+
+	st.docs = make(map[string]*document)
+	st.order = nil
+	return nil
+}
+
+// Cleanup - removes sops files under prefix which were not saved during this run
+func (st *Store) Cleanup() (bool, error) {
+	var stale []string
+	err := filepath.Walk(filepath.Join(st.root, st.prefix), func(p string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(p) != fileExt || st.docs[p] != nil {
+			return nil
+		}
+
+		// other yaml files of repository are never touched
+		data, err := os.ReadFile(p)
+		if err == nil && isSopsFile(data) {
+			stale = append(stale, p)
+		}
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range stale {
+		st.logger.WithField("type", "cleaner").
+			WithField("path", p).
+			Info("sops file will be deleted")
+
+		if st.dryrun {
+			continue
+		}
+
+		err = os.Remove(p)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return len(stale) > 0 && !st.dryrun, nil
+}
+
+// NewStore - recipients and encryptedRegex override .sops.yaml creation rules,
+// config is looked up from output directory upwards if configPath is empty
+func NewStore(outputPath, configPath string, recipients []string, encryptedRegex, layout, format, filter, prefix string,
+	dryrun bool, logger *logrus.Logger) (st *Store, err error) {
+	root, err := filepath.Abs(outputPath)
+	if err != nil {
+		return
+	}
+
+	if prefix == "" {
+		prefix = "enpass"
+	}
+
+	st = &Store{
+		root:       root,
+		prefix:     prefix,
+		layout:     utils.FirstNonEmpty(layout, LayoutItem),
+		format:     utils.FirstNonEmpty(format, FormatYAML),
+		identities: loadIdentities(),
+		docs:       make(map[string]*document),
+		items:      utils.NewUniqueStrings(logger),
+		dryrun:     dryrun,
+		logger:     logger,
+	}
+
+	if st.layout != LayoutItem && st.layout != LayoutFolder {
+		return nil, fmt.Errorf("invalid sops layout: '%s'", st.layout)
+	}
+	if st.format != FormatYAML && st.format != FormatKubernetes {
+		return nil, fmt.Errorf("invalid sops format: '%s'", st.format)
+	}
+
+	if filter != "" {
+		st.filter, err = regexp.Compile(filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sops filter: %s", err.Error())
+		}
+	}
+
+	if len(recipients) > 0 {
+		if encryptedRegex == "" && st.format == FormatKubernetes {
+			encryptedRegex = kubernetesEncryptedRegex
+		}
+
+		r, err := newRule(recipients, encryptedRegex, "")
+		if err != nil {
+			return nil, err
+		}
+		st.rule = &r
+		return st, nil
+	}
+
+	if configPath == "" {
+		configPath = findConfig(root)
+	}
+	if configPath == "" {
+		return nil, fmt.Errorf("sops age recipients are not set and %s is not found", configFile)
+	}
+
+	st.config, err = loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
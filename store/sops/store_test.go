@@ -0,0 +1,246 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+func testLogger() *logrus.Logger {
+	var l = logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+// testIdentity - generates age identity and makes it the only one visible to store
+func testIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOPS_AGE_KEY", id.String())
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join(t.TempDir(), "keys.txt"))
+	return id
+}
+
+var sopsValueRe = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:str\]$`)
+
+// sopsOpen - decrypts value the way sops does: AES256-GCM with 32 bytes iv,
+// tag appended to data and additional data given by caller
+func sopsOpen(t *testing.T, value string, key []byte, aad string) string {
+	t.Helper()
+	var m = sopsValueRe.FindStringSubmatch(value)
+	if m == nil {
+		t.Fatalf("value is not sops encrypted: %q", value)
+	}
+
+	var raw [3][]byte
+	for i := range raw {
+		b, err := base64.StdEncoding.DecodeString(m[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw[i] = b
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(raw[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := gcm.Open(nil, raw[1], append(raw[0], raw[2]...), []byte(aad))
+	if err != nil {
+		t.Fatalf("cannot decrypt %q with additional data %q: %s", value, aad, err.Error())
+	}
+	return string(plain)
+}
+
+// sopsDecrypt - decrypts sops file written by store and verifies its mac;
+// returns "key:path: value" lines of all values and values left unencrypted
+func sopsDecrypt(t *testing.T, data []byte, id age.Identity) (values, plain []string) {
+	t.Helper()
+	var doc struct {
+		Sops struct {
+			Age []struct {
+				Recipient string `yaml:"recipient"`
+				Enc       string `yaml:"enc"`
+			} `yaml:"age"`
+			LastModified string `yaml:"lastmodified"`
+			Mac          string `yaml:"mac"`
+			Version      string `yaml:"version"`
+		} `yaml:"sops"`
+	}
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Sops.Age) != 1 || doc.Sops.Version == "" {
+		t.Fatalf("unexpected sops metadata: %+v", doc.Sops)
+	}
+	if _, err = time.Parse(time.RFC3339, doc.Sops.LastModified); err != nil {
+		t.Errorf("invalid lastmodified: %s", err.Error())
+	}
+
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(doc.Sops.Age[0].Enc)), id)
+	if err != nil {
+		t.Fatalf("cannot decrypt data key: %s", err.Error())
+	}
+	key, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var root yaml.Node
+	err = yaml.Unmarshal(data, &root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mac is sha512 of all values in document order
+	var h = sha512.New()
+	var visit func(n *yaml.Node, path []string)
+	visit = func(n *yaml.Node, path []string) {
+		switch n.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, c := range n.Content {
+				visit(c, path)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if len(path) == 0 && n.Content[i].Value == "sops" {
+					continue
+				}
+				visit(n.Content[i+1], append(path[:len(path):len(path)], n.Content[i].Value))
+			}
+		case yaml.ScalarNode:
+			var v = n.Value
+			if sopsValueRe.MatchString(v) {
+				v = sopsOpen(t, v, key, strings.Join(path, ":")+":")
+			} else {
+				plain = append(plain, strings.Join(path, ":"))
+			}
+			h.Write([]byte(v))
+			values = append(values, fmt.Sprintf("%s: %s", strings.Join(path, ":"), v))
+		}
+	}
+	visit(&root, nil)
+
+	var mac = sopsOpen(t, doc.Sops.Mac, key, doc.Sops.LastModified)
+	if mac != fmt.Sprintf("%X", h.Sum(nil)) {
+		t.Error("sops mac does not match decrypted values")
+	}
+	return
+}
+
+func TestStoreWritesDecryptableFiles(t *testing.T) {
+	var tests = []struct {
+		name   string
+		format string
+		file   string
+		values []string
+		plain  []string
+	}{
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			file:   "enpass/login/github.yaml",
+			values: []string{
+				"username: user",
+				"password: secret",
+				"note: first\nsecond",
+				"attachments:file.txt: Y29udGVudA==",
+			},
+		},
+		{
+			name:   "kubernetes",
+			format: FormatKubernetes,
+			file:   "enpass/login/github.yaml",
+			values: []string{
+				"apiVersion: v1",
+				"kind: Secret",
+				"metadata:name: enpass-login-github",
+				"type: Opaque",
+				"stringData:username: user",
+				"stringData:password: secret",
+				"stringData:note: first\nsecond",
+				"data:file.txt: Y29udGVudA==",
+			},
+			plain: []string{"apiVersion", "kind", "metadata:name", "type"},
+		},
+	}
+
+	var fields = []field.FieldInterface{
+		field.NewUsernameField("username", "user"),
+		field.NewPasswordField("password", "secret"),
+		field.NewSimpleField("note", []byte("first\nsecond"), true, false),
+		field.NewAttachmentField("file.txt", []byte("content")),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id = testIdentity(t)
+			var dir = t.TempDir()
+			var recipients = []string{id.Recipient().String()}
+
+			st, err := NewStore(dir, "", recipients, "", LayoutItem, tt.format, "", "enpass", false, testLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = st.Save(fields, "login/github"); err != nil {
+				t.Fatal(err)
+			}
+			if err = st.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			var file = filepath.Join(dir, filepath.FromSlash(tt.file))
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values, plain := sopsDecrypt(t, data, id)
+			if strings.Join(values, "\n") != strings.Join(tt.values, "\n") {
+				t.Errorf("decrypted values:\n%s\nexpected:\n%s", strings.Join(values, "\n"), strings.Join(tt.values, "\n"))
+			}
+			if strings.Join(plain, ",") != strings.Join(tt.plain, ",") {
+				t.Errorf("unencrypted values = %v, expected %v", plain, tt.plain)
+			}
+
+			// existing file is decrypted with identity and kept as is
+			st, err = NewStore(dir, "", recipients, "", LayoutItem, tt.format, "", "enpass", false, testLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = st.Save(fields, "login/github"); err != nil {
+				t.Fatal(err)
+			}
+			if err = st.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if current, _ := os.ReadFile(file); !bytes.Equal(current, data) {
+				t.Error("unchanged sops file has been rewritten")
+			}
+		})
+	}
+}
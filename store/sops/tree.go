@@ -0,0 +1,212 @@
+package sops
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+)
+
+const sopsVersion = "3.7.3"
+
+func scalar(v string) *yaml.Node {
+	var n = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	if strings.Contains(v, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+	return n
+}
+
+// mapping - builds mapping node from key and value nodes pairs
+func mapping(kv ...interface{}) *yaml.Node {
+	var n = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(kv); i += 2 {
+		var v *yaml.Node
+		switch val := kv[i+1].(type) {
+		case string:
+			v = scalar(val)
+		case *yaml.Node:
+			v = val
+		}
+		n.Content = append(n.Content, scalar(kv[i].(string)), v)
+	}
+	return n
+}
+
+// setKey - appends key to mapping node
+func setKey(n *yaml.Node, k string, v *yaml.Node) {
+	n.Content = append(n.Content, scalar(k), v)
+}
+
+// walk - calls fn for every scalar value with its keys path, sops metadata is skipped
+func walk(n *yaml.Node, path []string, fn func(n *yaml.Node, path []string) error) error {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			err := walk(c, path, fn)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			var k = n.Content[i].Value
+			if len(path) == 0 && k == "sops" {
+				continue
+			}
+			err := walk(n.Content[i+1], append(path[:len(path):len(path)], k), fn)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return fn(n, path)
+	}
+	return nil
+}
+
+// flatten - returns path and value pairs of tree for comparison
+func flatten(n *yaml.Node) (out []string, err error) {
+	err = walk(n, nil, func(n *yaml.Node, path []string) error {
+		out = append(out, strings.Join(path, "\x00")+"\x00"+n.Value)
+		return nil
+	})
+	return
+}
+
+func aad(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
+
+// encryptTree - encrypts values of plain tree in place and appends sops metadata
+func encryptTree(root *yaml.Node, r rule) error {
+	var key = make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return err
+	}
+
+	var values []string
+	err = walk(root, nil, func(n *yaml.Node, path []string) error {
+		values = append(values, n.Value)
+		if !r.encrypted(path) {
+			return nil
+		}
+
+		enc, err := encryptValue(n.Value, key, aad(path))
+		if err != nil {
+			return err
+		}
+		n.Value, n.Tag, n.Style = enc, "!!str", 0
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var recipients = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for i, rcpt := range r.recipients {
+		enc, err := encryptDataKey(key, rcpt)
+		if err != nil {
+			return err
+		}
+		recipients.Content = append(recipients.Content, mapping(
+			"recipient", r.recipientStrings[i],
+			"enc", enc,
+		))
+	}
+
+	var lastModified = time.Now().UTC().Format(time.RFC3339)
+	mac, err := encryptValue(computeMac(values), key, lastModified)
+	if err != nil {
+		return err
+	}
+
+	var empty = func() *yaml.Node {
+		return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	}
+	var meta = mapping(
+		"kms", empty(),
+		"gcp_kms", empty(),
+		"azure_kv", empty(),
+		"hc_vault", empty(),
+		"age", recipients,
+		"lastmodified", lastModified,
+		"mac", mac,
+		"pgp", empty(),
+	)
+	if r.encryptedRegex != nil {
+		setKey(meta, "encrypted_regex", scalar(r.encryptedRegex.String()))
+	} else {
+		setKey(meta, "unencrypted_suffix", scalar(r.unencryptedSuffix))
+	}
+	setKey(meta, "version", scalar(sopsVersion))
+
+	setKey(root, "sops", meta)
+	return nil
+}
+
+// decryptTree - decrypts values of existing sops file in place
+func decryptTree(doc *yaml.Node, identities []age.Identity) error {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return errors.New("sops file is not a yaml mapping")
+	}
+
+	var meta *yaml.Node
+	var root = doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "sops" {
+			meta = root.Content[i+1]
+		}
+	}
+	if meta == nil {
+		return errors.New("sops metadata is not found")
+	}
+
+	var metadata struct {
+		Age []struct {
+			Enc string `yaml:"enc"`
+		} `yaml:"age"`
+	}
+	err := meta.Decode(&metadata)
+	if err != nil {
+		return err
+	}
+
+	var key []byte
+	for _, a := range metadata.Age {
+		key, err = decryptDataKey(a.Enc, identities)
+		if err == nil {
+			break
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("cannot decrypt sops data key")
+	}
+
+	return walk(root, nil, func(n *yaml.Node, path []string) error {
+		if !encValueRe.MatchString(n.Value) {
+			return nil
+		}
+		v, err := decryptValue(n.Value, key, aad(path))
+		if err != nil {
+			return err
+		}
+		n.Value = v
+		return nil
+	})
+}
+
+// isSopsFile - checks whether yaml document has sops metadata
+func isSopsFile(data []byte) bool {
+	var doc map[string]interface{}
+	if yaml.Unmarshal(data, &doc) != nil {
+		return false
+	}
+	_, ok := doc["sops"]
+	return ok
+}
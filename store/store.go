@@ -9,6 +9,13 @@ type StoreDestination interface {
 	Save(fields []field.FieldInterface, p string) (bool, error)
 }
 
+// StoreDiscarder - destination which holds resources, Discard releases them
+// without writing or committing pending changes; it is used instead of Close
+// for destinations which failed during the run
+type StoreDiscarder interface {
+	Discard() error
+}
+
 // StoreSource -
 type StoreSource interface {
 	LoadData() (o []StoreSourceItem, err error)
@@ -19,3 +26,8 @@ type StoreSourceItem interface {
 	GetSecretPath() (string, error)
 	GetFields() (o []field.FieldInterface, err error)
 }
+
+// StoreSourceFile - source which is read from single file
+type StoreSourceFile interface {
+	GetSourcePath() string
+}
@@ -0,0 +1,112 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// errNotFound - returned for 404 responses
+var errNotFound = errors.New("vault path is not found")
+
+// client - minimal vault http api client
+type client struct {
+	address   string
+	token     string
+	namespace string
+	http      *http.Client
+}
+
+// response - generic vault response
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []string        `json:"errors"`
+}
+
+// escapePath - escapes every path segment
+func escapePath(p string) string {
+	var parts = strings.Split(strings.Trim(p, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (c *client) do(ctx context.Context, method, p string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	var u = c.address + "/v1/" + p
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Vault-Token", c.token)
+	req.Header.Set("X-Vault-Request", "true")
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var r response
+	if len(b) > 0 {
+		_ = json.Unmarshal(b, &r)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case resp.StatusCode >= 400:
+		var msg = strings.Join(r.Errors, "; ")
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("vault %s %s: %d %s", method, p, resp.StatusCode, msg)
+	}
+
+	if out == nil || len(r.Data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(r.Data, out)
+}
+
+func newClient(address, token, namespace string) *client {
+	return &client{
+		address:   strings.TrimRight(address, "/"),
+		token:     token,
+		namespace: namespace,
+		http:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
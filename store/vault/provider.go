@@ -0,0 +1,40 @@
+package vault
+
+import (
+	"github.com/revengel/enpass2gopass/store"
+	"github.com/revengel/enpass2gopass/utils"
+)
+
+// DestinationName -
+const DestinationName = "vaultDestination"
+
+func init() {
+	store.RegisterDestination(store.DestinationProvider{
+		Provider: store.Provider{
+			Name:        DestinationName,
+			Description: "HashiCorp Vault KV v2 secrets engine",
+			FlagPrefix:  "destination-vault",
+			Options: []store.Option{
+				store.StringOption("address", "", "destination vault address, VAULT_ADDR if empty"),
+				store.StringOption("token-env", "VAULT_TOKEN", "environment variable with destination vault token, ~/.vault-token is used if empty"),
+				store.StringOption("token-file", "", "file with destination vault token"),
+				store.StringOption("namespace", "", "destination vault namespace, VAULT_NAMESPACE if empty"),
+				store.StringOption("mount", "secret", "destination vault kv v2 mount path"),
+				store.StringOption("attachments", AttachmentsInline, "destination vault attachments mode: inline (base64 keys) or path (separate secrets)"),
+			},
+		},
+		New: func(env store.Env, opts store.Options) (store.StoreDestination, error) {
+			token, _, err := utils.ReadPassword(opts.String("token-env"), opts.String("token-file"), false, "")
+			if err != nil {
+				return nil, err
+			}
+
+			st, err := NewStore(env.Ctx, opts.String("address"), token, opts.String("namespace"), opts.String("mount"),
+				opts.String("attachments"), env.Prefix, env.DryRun, env.Logger)
+			if err != nil {
+				return nil, err
+			}
+			return st, nil
+		},
+	})
+}
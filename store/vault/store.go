@@ -0,0 +1,288 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/revengel/enpass2gopass/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// AttachmentsInline - attachments are stored base64-encoded in item secret
+	AttachmentsInline = "inline"
+	// AttachmentsPath - attachments are stored as separate secrets under item path
+	AttachmentsPath = "path"
+
+	attachmentKeyPrefix = "attachment_"
+	attachmentsDir      = "attachments"
+)
+
+// secretData - kv v2 "data" response
+type secretData struct {
+	Data     map[string]interface{} `json:"data"`
+	Metadata struct {
+		Version int `json:"version"`
+	} `json:"metadata"`
+}
+
+// Store - writes secrets into vault kv v2 engine
+type Store struct {
+	ctx         context.Context
+	client      *client
+	mount       string
+	prefix      string
+	attachments string
+	items       *utils.UniqueStrings
+	written     map[string]bool
+	dryrun      bool
+	logger      *logrus.Logger
+}
+
+// Close -
+func (st *Store) Close() error {
+	return nil
+}
+
+// get - reads latest version of secret, nil if secret does not exist or is deleted
+func (st *Store) get(p string) (*secretData, error) {
+	var out secretData
+	err := st.client.do(st.ctx, "GET", st.mount+"/data/"+escapePath(p), nil, nil, &out)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// put - writes new version of secret, cas guards existing secret against concurrent writes
+func (st *Store) put(p string, data map[string]interface{}, cas int) error {
+	var in = map[string]interface{}{"data": data}
+	if cas > 0 {
+		in["options"] = map[string]interface{}{"cas": cas}
+	}
+	return st.client.do(st.ctx, "POST", st.mount+"/data/"+escapePath(p), nil, in, nil)
+}
+
+// list - lists secret paths under p recursively
+func (st *Store) list(p string) (out []string, err error) {
+	var keys struct {
+		Keys []string `json:"keys"`
+	}
+
+	err = st.client.do(st.ctx, "GET", st.mount+"/metadata/"+escapePath(p), url.Values{"list": {"true"}}, nil, &keys)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+
+	for _, k := range keys.Keys {
+		var full = path.Join(p, k)
+		if !strings.HasSuffix(k, "/") {
+			out = append(out, full)
+			continue
+		}
+
+		sub, err := st.list(full)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sub...)
+	}
+
+	return
+}
+
+// remove - deletes all versions and metadata of secret
+func (st *Store) remove(p string) error {
+	return st.client.do(st.ctx, "DELETE", st.mount+"/metadata/"+escapePath(p), nil, nil, nil)
+}
+
+// saveSecret - writes secret if its content differs from latest version
+func (st *Store) saveSecret(data map[string]interface{}, p string) (bool, error) {
+	st.written[p] = true
+	var l = st.logger.WithField("vaultkey", p)
+
+	current, err := st.get(p)
+	if err != nil {
+		return false, err
+	}
+
+	var cas int
+	if current != nil {
+		if reflect.DeepEqual(current.Data, data) {
+			l.Debug("vault secret already in actual state")
+			return false, nil
+		}
+		cas = current.Metadata.Version
+	}
+
+	l.Info("secret will be updated")
+	if st.dryrun {
+		return true, nil
+	}
+
+	err = st.put(p, data, cas)
+	if err != nil {
+		return false, err
+	}
+
+	l.Info("secret has been updated")
+	return true, nil
+}
+
+// uniqueKey - returns key which is not used in data yet
+func uniqueKey(data map[string]interface{}, k string) string {
+	var out = k
+	for i := 2; ; i++ {
+		if _, ok := data[out]; !ok {
+			return out
+		}
+		out = fmt.Sprintf("%s_%d", k, i)
+	}
+}
+
+// Save -
+func (st *Store) Save(fields []field.FieldInterface, p string) (bool, error) {
+	p = st.items.Unique(p)
+	var secretPath = path.Join(st.prefix, filepath.ToSlash(p))
+
+	var data = make(map[string]interface{})
+	var attachments = make(map[string]map[string]interface{})
+	for _, f := range fields {
+		var k, v = f.GetKey(), f.GetValueString()
+		if f.IsType(field.SecretAttachmentField) {
+			var encoded = base64.StdEncoding.EncodeToString(f.GetValue())
+			if st.attachments == AttachmentsInline {
+				data[uniqueKey(data, attachmentKeyPrefix+k)] = encoded
+				continue
+			}
+
+			var name = utils.FirstNonEmpty(utils.Transliterate(k), "attachment")
+			for i := 2; attachments[name] != nil; i++ {
+				name = fmt.Sprintf("%s_%d", utils.Transliterate(k), i)
+			}
+			attachments[name] = map[string]interface{}{
+				"filename": k,
+				"data":     encoded,
+				"sha256":   utils.GetHashFromBytes(f.GetValue()),
+			}
+			continue
+		}
+
+		if k == "" || v == "" {
+			continue
+		}
+		data[uniqueKey(data, k)] = v
+	}
+
+	out, err := st.saveSecret(data, secretPath)
+	if err != nil {
+		return out, err
+	}
+
+	for name, a := range attachments {
+		changed, err := st.saveSecret(a, path.Join(secretPath, attachmentsDir, name))
+		if err != nil {
+			return out, err
+		}
+		out = out || changed
+	}
+
+	return out, nil
+}
+
+// Cleanup - deletes secrets under prefix which were not saved during this run
+func (st *Store) Cleanup() (bool, error) {
+	keys, err := st.list(st.prefix)
+	if err != nil {
+		return false, err
+	}
+
+	var deleted bool
+	for _, k := range keys {
+		if st.written[k] {
+			continue
+		}
+
+		st.logger.WithField("type", "cleaner").
+			WithField("vaultkey", k).
+			Info("vault secret will be deleted")
+
+		if st.dryrun {
+			continue
+		}
+
+		err = st.remove(k)
+		if err != nil {
+			return deleted, err
+		}
+		deleted = true
+	}
+
+	return deleted, nil
+}
+
+// readTokenFile - reads token saved by vault cli login
+func readTokenFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	b, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// NewStore - address and token default to VAULT_ADDR and VAULT_TOKEN (or ~/.vault-token)
+func NewStore(ctx context.Context, address, token, namespace, mount, attachments, prefix string, dryrun bool, logger *logrus.Logger) (st *Store, err error) {
+	address = utils.FirstNonEmpty(address, os.Getenv("VAULT_ADDR"))
+	if address == "" {
+		return nil, errors.New("vault address is not set")
+	}
+
+	token = utils.FirstNonEmpty(token, os.Getenv("VAULT_TOKEN"), readTokenFile())
+	if token == "" {
+		return nil, errors.New("vault token is not set")
+	}
+
+	switch attachments {
+	case "":
+		attachments = AttachmentsInline
+	case AttachmentsInline, AttachmentsPath:
+	default:
+		return nil, fmt.Errorf("invalid vault attachments mode: '%s'", attachments)
+	}
+
+	if prefix == "" {
+		prefix = "enpass"
+	}
+
+	return &Store{
+		ctx:         ctx,
+		client:      newClient(address, token, utils.FirstNonEmpty(namespace, os.Getenv("VAULT_NAMESPACE"))),
+		mount:       escapePath(utils.FirstNonEmpty(mount, "secret")),
+		prefix:      strings.Trim(prefix, "/"),
+		attachments: attachments,
+		items:       utils.NewUniqueStrings(logger),
+		written:     make(map[string]bool),
+		dryrun:      dryrun,
+		logger:      logger,
+	}, nil
+}
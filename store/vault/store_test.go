@@ -0,0 +1,300 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/revengel/enpass2gopass/field"
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Logger {
+	var l = logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+type kvSecret struct {
+	data    map[string]interface{}
+	version int
+}
+
+// kvServer - in-memory kv v2 engine mounted at "secret"
+type kvServer struct {
+	mu      sync.Mutex
+	secrets map[string]*kvSecret
+	writes  []string
+	deletes []string
+}
+
+func (s *kvServer) reply(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func (s *kvServer) fail(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{msg}})
+}
+
+func (s *kvServer) list(p string) (keys []string) {
+	var seen = make(map[string]bool)
+	for k := range s.secrets {
+		if !strings.HasPrefix(k, p+"/") {
+			continue
+		}
+		var rest = strings.TrimPrefix(k, p+"/")
+		if dir, _, found := strings.Cut(rest, "/"); found {
+			rest = dir + "/"
+		}
+		if !seen[rest] {
+			seen[rest] = true
+			keys = append(keys, rest)
+		}
+	}
+	sort.Strings(keys)
+	return
+}
+
+func (s *kvServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != "token" {
+		s.fail(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	p, err := url.PathUnescape(r.URL.EscapedPath())
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(p, "/v1/secret/data/"):
+		p = strings.TrimPrefix(p, "/v1/secret/data/")
+		var sec = s.secrets[p]
+		switch r.Method {
+		case http.MethodGet:
+			if sec == nil {
+				s.fail(w, http.StatusNotFound, "")
+				return
+			}
+			s.reply(w, http.StatusOK, map[string]interface{}{
+				"data":     sec.data,
+				"metadata": map[string]interface{}{"version": sec.version},
+			})
+		case http.MethodPost:
+			var in struct {
+				Data    map[string]interface{} `json:"data"`
+				Options struct {
+					CAS *int `json:"cas"`
+				} `json:"options"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				s.fail(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			var version int
+			if sec != nil {
+				version = sec.version
+			}
+			if in.Options.CAS != nil && *in.Options.CAS != version || in.Options.CAS == nil && sec != nil {
+				s.fail(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+				return
+			}
+
+			s.secrets[p] = &kvSecret{data: in.Data, version: version + 1}
+			s.writes = append(s.writes, p)
+			s.reply(w, http.StatusOK, map[string]interface{}{"version": version + 1})
+		default:
+			s.fail(w, http.StatusMethodNotAllowed, "")
+		}
+	case strings.HasPrefix(p, "/v1/secret/metadata/"):
+		p = strings.TrimPrefix(p, "/v1/secret/metadata/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("list") == "true":
+			var keys = s.list(p)
+			if len(keys) == 0 {
+				s.fail(w, http.StatusNotFound, "")
+				return
+			}
+			s.reply(w, http.StatusOK, map[string]interface{}{"keys": keys})
+		case r.Method == http.MethodDelete:
+			delete(s.secrets, p)
+			s.deletes = append(s.deletes, p)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			s.fail(w, http.StatusMethodNotAllowed, "")
+		}
+	default:
+		s.fail(w, http.StatusNotFound, "")
+	}
+}
+
+// testServer - starts kv v2 server with initial secrets
+func testServer(t *testing.T, secrets map[string]map[string]interface{}) *kvServer {
+	t.Helper()
+	var s = &kvServer{secrets: make(map[string]*kvSecret)}
+	for k, v := range secrets {
+		s.secrets[k] = &kvSecret{data: v, version: 1}
+	}
+
+	var srv = httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	t.Setenv("VAULT_ADDR", srv.URL)
+	return s
+}
+
+func testStore(t *testing.T, attachments string, dryrun bool) *Store {
+	t.Helper()
+	st, err := NewStore(context.Background(), "", "token", "", "secret", attachments, "enpass", dryrun, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func itemFields(password string) []field.FieldInterface {
+	return []field.FieldInterface{
+		field.NewUsernameField("", "user"),
+		field.NewPasswordField("", password),
+		field.NewAttachmentField("file.txt", []byte("content")),
+	}
+}
+
+func TestStoreCreatesSecret(t *testing.T) {
+	var srv = testServer(t, nil)
+	var st = testStore(t, AttachmentsPath, false)
+
+	changed, err := st.Save(itemFields("secret"), "login/github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("new secret is not reported as changed")
+	}
+
+	var item = srv.secrets["enpass/login/github"]
+	if item == nil {
+		t.Fatalf("secret has not been written, secrets: %v", srv.secrets)
+	}
+	var expected = map[string]interface{}{"username": "user", "password": "secret"}
+	if !reflect.DeepEqual(item.data, expected) {
+		t.Errorf("secret data = %v, expected %v", item.data, expected)
+	}
+
+	var attachment = srv.secrets["enpass/login/github/attachments/file_txt"]
+	if attachment == nil {
+		t.Fatalf("attachment has not been written, secrets: %v", srv.secrets)
+	}
+	if attachment.data["filename"] != "file.txt" || attachment.data["data"] != "Y29udGVudA==" {
+		t.Errorf("unexpected attachment data: %v", attachment.data)
+	}
+}
+
+func TestStoreSkipsUnchangedSecret(t *testing.T) {
+	var srv = testServer(t, map[string]map[string]interface{}{
+		"enpass/login/github": {"username": "user", "password": "secret", "attachment_file.txt": "Y29udGVudA=="},
+		"enpass/login/gitlab": {"username": "user", "password": "old"},
+	})
+	var st = testStore(t, AttachmentsInline, false)
+
+	changed, err := st.Save(itemFields("secret"), "login/github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("unchanged secret is reported as changed")
+	}
+
+	// existing secret is updated with cas set to its current version
+	changed, err = st.Save(itemFields("new"), "login/gitlab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("updated secret is not reported as changed")
+	}
+
+	if !reflect.DeepEqual(srv.writes, []string{"enpass/login/gitlab"}) {
+		t.Errorf("writes = %v, expected only updated secret", srv.writes)
+	}
+	if v := srv.secrets["enpass/login/gitlab"].version; v != 2 {
+		t.Errorf("secret version = %d, expected 2", v)
+	}
+}
+
+func TestStoreCleanup(t *testing.T) {
+	var srv = testServer(t, map[string]map[string]interface{}{
+		"enpass/login/github":                     {"username": "user", "password": "secret"},
+		"enpass/login/stale":                      {"password": "old"},
+		"enpass/login/stale/attachments/file_txt": {"data": "b2xk"},
+		"other/secret":                            {"password": "kept"},
+	})
+	var st = testStore(t, AttachmentsInline, false)
+
+	_, err := st.Save([]field.FieldInterface{
+		field.NewUsernameField("", "user"),
+		field.NewPasswordField("", "secret"),
+	}, "login/github")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := st.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted {
+		t.Error("cleanup does not report deleted secrets")
+	}
+
+	sort.Strings(srv.deletes)
+	var expected = []string{"enpass/login/stale", "enpass/login/stale/attachments/file_txt"}
+	if !reflect.DeepEqual(srv.deletes, expected) {
+		t.Errorf("deletes = %v, expected %v", srv.deletes, expected)
+	}
+	if srv.secrets["enpass/login/github"] == nil || srv.secrets["other/secret"] == nil {
+		t.Errorf("saved or foreign secret has been deleted, secrets: %v", srv.secrets)
+	}
+}
+
+func TestStoreDryRun(t *testing.T) {
+	var srv = testServer(t, map[string]map[string]interface{}{
+		"enpass/login/stale": {"password": "old"},
+	})
+	var st = testStore(t, AttachmentsPath, true)
+
+	changed, err := st.Save(itemFields("secret"), "login/github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("new secret is not reported as changed in dry run")
+	}
+
+	_, err = st.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srv.writes) > 0 || len(srv.deletes) > 0 {
+		t.Errorf("dry run modified vault: writes %v, deletes %v", srv.writes, srv.deletes)
+	}
+	if srv.secrets["enpass/login/stale"] == nil {
+		t.Error("stale secret has been deleted in dry run")
+	}
+}
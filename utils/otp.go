@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+)
+
+// otpParams - otpauth uri parameters which are kept as is
+var otpParams = []string{"algorithm", "digits", "period", "counter"}
+
+// normalizeOTPSecret - removes separators and padding of base32 secret
+func normalizeOTPSecret(in string) (string, bool) {
+	var out = strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "\t", "", "=", "").Replace(in))
+	if out == "" {
+		return "", false
+	}
+
+	_, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(out)
+	return out, err == nil
+}
+
+// OTPAuthURI - converts bare base32 secret or otpauth uri into otpauth uri with
+// issuer and account label; algorithm, digits, period and counter of uri are kept.
+// It returns false if value is neither valid secret nor otpauth uri
+func OTPAuthURI(value, issuer, account string) (string, bool) {
+	value = strings.TrimSpace(value)
+
+	var otpType = "totp"
+	var secret string
+	var params = url.Values{}
+	if strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return "", false
+		}
+
+		otpType = strings.ToLower(u.Host)
+		if otpType != "totp" && otpType != "hotp" {
+			return "", false
+		}
+
+		var q = u.Query()
+		secret = q.Get("secret")
+		for _, k := range otpParams {
+			if v := q.Get(k); v != "" {
+				params.Set(k, v)
+			}
+		}
+
+		// label and issuer of uri are more precise than item title and username
+		var labelIssuer string
+		if label := strings.TrimPrefix(u.Path, "/"); label != "" {
+			if i := strings.Index(label, ":"); i >= 0 {
+				labelIssuer = strings.TrimSpace(label[:i])
+				label = label[i+1:]
+			}
+			account = FirstNonEmpty(strings.TrimSpace(label), account)
+		}
+		issuer = FirstNonEmpty(q.Get("issuer"), labelIssuer, issuer)
+	} else {
+		secret = value
+	}
+
+	secret, ok := normalizeOTPSecret(secret)
+	if !ok {
+		return "", false
+	}
+	params.Set("secret", secret)
+
+	var label = FirstNonEmpty(account, issuer, otpType)
+	if issuer != "" {
+		params.Set("issuer", issuer)
+		if account != "" {
+			label = issuer + ":" + account
+		}
+	}
+
+	// spaces are encoded as %20 like authenticator apps expect
+	var u = url.URL{
+		Scheme:   "otpauth",
+		Host:     otpType,
+		Path:     "/" + label,
+		RawQuery: strings.ReplaceAll(params.Encode(), "+", "%20"),
+	}
+	return u.String(), true
+}
+
+// OTPIssuer - issuer name from item title or host of its url
+func OTPIssuer(title, rawURL string) string {
+	if title != "" {
+		return title
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		u, err = url.Parse("https://" + rawURL)
+	}
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrNoTerminal - returned when password prompt is requested without terminal
+var ErrNoTerminal = errors.New("stdin is not a terminal")
+
+// ReadPasswordFile - reads password from the first line of file
+func ReadPasswordFile(p string) (string, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(strings.SplitN(string(b), "\n", 2)[0], "\r"), nil
+}
+
+// PromptPassword - reads password from terminal without echo
+func PromptPassword(prompt string) (string, error) {
+	var fd = int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", ErrNoTerminal
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ReadPassword - reads password from file, environment variable or terminal prompt;
+// ok is false when no password source is configured
+func ReadPassword(envName, filePath string, prompt bool, promptText string) (password string, ok bool, err error) {
+	if filePath != "" {
+		password, err = ReadPasswordFile(filePath)
+		if err != nil {
+			return "", false, fmt.Errorf("cannot read password file '%s': %s", filePath, err.Error())
+		}
+		return password, true, nil
+	}
+
+	if envName != "" {
+		if v, exists := os.LookupEnv(envName); exists {
+			return v, true, nil
+		}
+	}
+
+	if !prompt {
+		return "", false, nil
+	}
+
+	password, err = PromptPassword(promptText)
+	if err != nil {
+		return "", false, fmt.Errorf("cannot prompt password: %s", err.Error())
+	}
+	return password, true, nil
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"crypto/sha256"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 )
 
@@ -13,6 +15,22 @@ func GetHashFromBytes(in []byte) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
+// GetFileHash - sha256 of file content, file is read by chunks
+func GetFileHash(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
 // GetHash -
 func GetHash(in string) string {
 	return GetHashFromBytes([]byte(in))
@@ -41,3 +59,24 @@ func InList[T comparable](elems []T, v T) bool {
 	}
 	return false
 }
+
+// FirstNonEmpty -
+func FirstNonEmpty(in ...string) string {
+	for _, s := range in {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// ParseTags - splits tags field value like "[a, b]" or "a;b"
+func ParseTags(v string) (out []string) {
+	v = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(v), "["), "]")
+	for _, t := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' }) {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return
+}